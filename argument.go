@@ -1,5 +1,18 @@
 package pgxmock
 
+import (
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
 // Argument interface allows to match
 // any argument in specific way when used with
 // ExpectedQuery and ExpectedExec expectations.
@@ -7,6 +20,12 @@ type Argument interface {
 	Match(interface{}) bool
 }
 
+// argumentDescriber is implemented by matchers presenting
+// mismatched values in a readable form, e.g. encoded bytes
+type argumentDescriber interface {
+	describe(actual any) string
+}
+
 // AnyArg will return an Argument which can
 // match any kind of arguments.
 //
@@ -21,3 +40,298 @@ func (a anyArgument) Match(_ interface{}) bool {
 	return true
 }
 
+// NotNilArg will return an Argument which can match any
+// argument sent to the database as a non-NULL value. Untyped
+// nil, nil pointers, maps and slices fail, as well as values
+// implementing driver.Valuer returning nil, e.g. pgtype.Int8{}.
+//
+// Useful to ensure a required value, e.g. a foreign key, is set.
+func NotNilArg() Argument {
+	return notNilArgument{}
+}
+
+type notNilArgument struct{}
+
+func (a notNilArgument) Match(v interface{}) bool {
+	if v == nil {
+		return false
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface:
+		if rv.IsNil() {
+			return false
+		}
+	}
+	if valuer, ok := v.(driver.Valuer); ok {
+		value, err := valuer.Value()
+		return err == nil && value != nil
+	}
+	return true
+}
+
+// ImplementsArg will return an Argument which can
+// match any argument assignable to the given interface type
+// and fails otherwise.
+//
+// Useful to ensure only values of a specific kind, e.g.
+// reflect.TypeOf((*Encrypted)(nil)).Elem(), are passed.
+func ImplementsArg(iface reflect.Type) Argument {
+	return implementsArgument{iface}
+}
+
+type implementsArgument struct {
+	iface reflect.Type
+}
+
+func (a implementsArgument) Match(v interface{}) bool {
+	if v == nil || a.iface == nil {
+		return false
+	}
+	return reflect.TypeOf(v).AssignableTo(a.iface)
+}
+
+// CaptureArg will return an Argument which can match any
+// argument of type T and stores the actual value into dest
+// for later inspection. NULL value is stored as a zero value.
+// The value is stored only once the whole expectation matches the call.
+//
+// Useful for generated values like UUIDs or timestamps, e.g.
+//
+//	var id uuid.UUID
+//	mock.ExpectExec("INSERT").WithArgs(pgxmock.CaptureArg(&id))
+func CaptureArg[T any](dest *T) Argument {
+	return captureArgument[T]{dest}
+}
+
+type captureArgument[T any] struct {
+	dest *T
+}
+
+func (a captureArgument[T]) Match(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	_, ok := v.(T)
+	return ok
+}
+
+func (a captureArgument[T]) capture(v interface{}) {
+	if v == nil {
+		var zero T
+		*a.dest = zero
+		return
+	}
+	*a.dest = v.(T)
+}
+
+// argumentCapturer is implemented by arguments storing the actual value,
+// it is called only for the matched arguments of the fulfilled expectation
+type argumentCapturer interface {
+	capture(v interface{})
+}
+
+// RegexArg will return an Argument which can match any
+// argument whose string representation, i.e. fmt.Sprint(v),
+// matches the regular expression. Panics if the pattern
+// can't be compiled, the same as regexp.MustCompile.
+//
+// Useful for format-constrained values like UUIDs or emails.
+func RegexArg(pattern string) Argument {
+	return regexArgument{regexp.MustCompile(pattern)}
+}
+
+type regexArgument struct {
+	re *regexp.Regexp
+}
+
+func (a regexArgument) Match(v interface{}) bool {
+	return a.re.MatchString(fmt.Sprint(v))
+}
+
+// TimeWithinArg will return an Argument which can match
+// time.Time, pgtype.Timestamptz or pgtype.Timestamp arguments
+// within the tolerance of the expected time. NULL never matches.
+//
+// Useful for time.Now() generated values, e.g. updated_at columns.
+func TimeWithinArg(expected time.Time, tolerance time.Duration) Argument {
+	return timeWithinArgument{expected, tolerance}
+}
+
+type timeWithinArgument struct {
+	expected  time.Time
+	tolerance time.Duration
+}
+
+func (a timeWithinArgument) Match(v interface{}) bool {
+	var actual time.Time
+	switch t := v.(type) {
+	case time.Time:
+		actual = t
+	case *time.Time:
+		if t == nil {
+			return false
+		}
+		actual = *t
+	case pgtype.Timestamptz:
+		if !t.Valid {
+			return false
+		}
+		actual = t.Time
+	case pgtype.Timestamp:
+		if !t.Valid {
+			return false
+		}
+		actual = t.Time
+	default:
+		return false
+	}
+	d := actual.Sub(a.expected)
+	return d >= -a.tolerance && d <= a.tolerance
+}
+
+// JSONArg will return an Argument which can match arguments
+// encoded as JSON, e.g. for json or jsonb columns, with the same
+// logical content as expected, regardless of the key order and
+// formatting. Both expected and actual values may be []byte,
+// string or json.RawMessage holding JSON, other values are
+// marshaled to JSON. Panics if the expected value is not valid JSON.
+func JSONArg(expected any) Argument {
+	v, err := jsonValue(expected)
+	if err != nil {
+		panic(fmt.Sprintf("pgxmock: invalid JSONArg value: %s", err))
+	}
+	return jsonArgument{v}
+}
+
+type jsonArgument struct {
+	expected any
+}
+
+func (a jsonArgument) Match(v interface{}) bool {
+	actual, err := jsonValue(v)
+	return err == nil && reflect.DeepEqual(a.expected, actual)
+}
+
+// jsonValue decodes the JSON encoded or marshaled value to interface{}
+func jsonValue(v any) (value any, err error) {
+	var data []byte
+	switch v := v.(type) {
+	case []byte:
+		data = v
+	case json.RawMessage:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		if data, err = json.Marshal(v); err != nil {
+			return nil, err
+		}
+	}
+	err = json.Unmarshal(data, &value)
+	return value, err
+}
+
+// BytesArg will return an Argument which can match []byte or
+// byte array arguments, e.g. hashes, equal to the hex encoded
+// expected value. Failures show the values hex encoded.
+// Panics if the expected value is not valid hex.
+func BytesArg(hexString string) Argument {
+	b, err := hex.DecodeString(hexString)
+	if err != nil {
+		panic(fmt.Sprintf("pgxmock: invalid BytesArg value: %s", err))
+	}
+	return bytesArgument{b, hex.EncodeToString, "hex"}
+}
+
+// Base64Arg will return an Argument which can match []byte or
+// byte array arguments equal to the standard base64 encoded
+// expected value. Failures show the values base64 encoded.
+// Panics if the expected value is not valid base64.
+func Base64Arg(s string) Argument {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		panic(fmt.Sprintf("pgxmock: invalid Base64Arg value: %s", err))
+	}
+	return bytesArgument{b, base64.StdEncoding.EncodeToString, "base64"}
+}
+
+type bytesArgument struct {
+	expected []byte
+	encode   func([]byte) string
+	encoding string
+}
+
+func (a bytesArgument) Match(v interface{}) bool {
+	actual, ok := bytesValue(v)
+	return ok && string(actual) == string(a.expected)
+}
+
+func (a bytesArgument) describe(v any) string {
+	actual, ok := bytesValue(v)
+	if !ok {
+		return fmt.Sprintf("expected %s '%s', but got %T - %+v", a.encoding, a.encode(a.expected), v, v)
+	}
+	return fmt.Sprintf("expected %s '%s', but got '%s'", a.encoding, a.encode(a.expected), a.encode(actual))
+}
+
+func (a bytesArgument) String() string {
+	return fmt.Sprintf("%s '%s'", a.encoding, a.encode(a.expected))
+}
+
+// bytesValue returns bytes of []byte or byte array, e.g. [32]byte
+func bytesValue(v any) ([]byte, bool) {
+	if b, ok := v.([]byte); ok {
+		return b, b != nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Array || rv.Type().Elem().Kind() != reflect.Uint8 {
+		return nil, false
+	}
+	b := make([]byte, rv.Len())
+	reflect.Copy(reflect.ValueOf(b), rv)
+	return b, true
+}
+
+// ProtoArg will return an Argument which can match []byte arguments holding
+// a serialized protobuf message equal to the expected one field by field.
+// The protobuf package functions are passed to avoid the dependency, e.g.
+//
+//	pgxmock.ProtoArg[proto.Message](want, proto.Unmarshal, proto.Equal)
+//
+// The actual value is unmarshaled into a new message of the expected type,
+// which must be a pointer as generated messages are.
+func ProtoArg[M any](expected M, unmarshal func(b []byte, m M) error, equal func(x, y M) bool) Argument {
+	return protoArgument[M]{expected, unmarshal, equal}
+}
+
+type protoArgument[M any] struct {
+	expected  M
+	unmarshal func(b []byte, m M) error
+	equal     func(x, y M) bool
+}
+
+func (a protoArgument[M]) decode(v interface{}) (actual M, err error) {
+	b, ok := v.([]byte)
+	if !ok {
+		return actual, fmt.Errorf("%T is not []byte", v)
+	}
+	t := reflect.TypeOf(a.expected)
+	if t == nil || t.Kind() != reflect.Pointer {
+		return actual, fmt.Errorf("expected message %T is not a pointer", a.expected)
+	}
+	actual = reflect.New(t.Elem()).Interface().(M)
+	return actual, a.unmarshal(b, actual)
+}
+
+func (a protoArgument[M]) Match(v interface{}) bool {
+	actual, err := a.decode(v)
+	return err == nil && a.equal(a.expected, actual)
+}
+
+func (a protoArgument[M]) describe(v any) string {
+	actual, err := a.decode(v)
+	if err != nil {
+		return fmt.Sprintf("expected message %+v, but got %T - %+v: %s", a.expected, v, v, err)
+	}
+	return fmt.Sprintf("expected message %+v, but got %+v", a.expected, actual)
+}
@@ -2,11 +2,17 @@ package pgxmock
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"reflect"
 	"testing"
 	"time"
 
 	pgx "github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -188,3 +194,264 @@ func TestAnyNamedArgument(t *testing.T) {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
 }
+
+type encrypted interface {
+	Encrypted() []byte
+}
+
+type secret string
+
+func (s secret) Encrypted() []byte {
+	return []byte(s)
+}
+
+func TestImplementsArgument(t *testing.T) {
+	t.Parallel()
+	mock, err := NewConn()
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	a := assert.New(t)
+	ifaceType := reflect.TypeOf((*encrypted)(nil)).Elem()
+
+	mock.ExpectExec("INSERT INTO users").
+		WithArgs("john", ImplementsArg(ifaceType)).
+		WillReturnResult(NewResult("INSERT", 1)).
+		Times(2)
+
+	_, err = mock.Exec(context.Background(), "INSERT INTO users(name, password) VALUES (?, ?)", "john", secret("pa$$"))
+	a.NoError(err)
+	_, err = mock.Exec(context.Background(), "INSERT INTO users(name, password) VALUES (?, ?)", "john", "pa$$")
+	a.Error(err, "raw string must not match encrypted interface")
+	_, err = mock.Exec(context.Background(), "INSERT INTO users(name, password) VALUES (?, ?)", "john", nil)
+	a.Error(err, "nil must not match encrypted interface")
+	a.Error(mock.ExpectationsWereMet())
+}
+
+func TestCaptureArgument(t *testing.T) {
+	t.Parallel()
+	mock, err := NewConn()
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	a := assert.New(t)
+
+	var (
+		created time.Time
+		name    any
+		id      int
+	)
+	mock.ExpectExec("INSERT INTO users").
+		WithArgs(CaptureArg(&name), CaptureArg(&created)).
+		WillReturnResult(NewResult("INSERT", 1))
+	mock.ExpectExec("DELETE FROM users").
+		WithArgs(CaptureArg(&id)).
+		WillReturnResult(NewResult("DELETE", 1))
+
+	now := time.Now()
+	_, err = mock.Exec(context.Background(), "INSERT INTO users(name, created_at) VALUES (?, ?)", "john", now)
+	a.NoError(err)
+	a.Equal("john", name)
+	a.Equal(now, created)
+
+	_, err = mock.Exec(context.Background(), "DELETE FROM users WHERE id = ?", "42")
+	a.Error(err, "argument of wrong type must not be matched")
+	id = 42
+	_, err = mock.Exec(context.Background(), "DELETE FROM users WHERE id = ?", nil)
+	a.NoError(err)
+	a.Zero(id, "NULL is captured as zero value")
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestCaptureArgumentOnlyMatched(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+	mock.MatchExpectationsInOrder(false)
+
+	var admin, guest string
+	mock.ExpectExec("INSERT INTO users").
+		WithArgs(CaptureArg(&admin), "admin").
+		WillReturnResult(NewResult("INSERT", 1)).
+		Maybe()
+	mock.ExpectExec("INSERT INTO users").
+		WithArgs(CaptureArg(&guest), "guest").
+		WillReturnResult(NewResult("INSERT", 1))
+	_, err := mock.Exec(ctx, "INSERT INTO users(name, role) VALUES ($1, $2)", "john", "guest")
+	a.NoError(err)
+	a.Empty(admin, "expectation not matched by other args must not capture")
+	a.Equal("john", guest)
+
+	var name string
+	mock.ExpectSendBatch(NewBatch().AddBatchElements(
+		NewBatchElement("INSERT INTO users", CaptureArg(&name)),
+		NewBatchElement("INSERT INTO users", "jane"),
+	)).Unordered()
+	b := &pgx.Batch{}
+	b.Queue("INSERT INTO users(name) VALUES ($1)", "jane")
+	b.Queue("INSERT INTO users(name) VALUES ($1)", "jack")
+	a.NoError(mock.SendBatch(ctx, b).Close())
+	a.Equal("jack", name, "value of the query the element was assigned to must be captured")
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestRegexArgument(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+	uuid := RegexArg(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+	a.True(uuid.Match("7d444840-9dc0-11d1-b245-5ffdce74fad2"))
+	a.False(uuid.Match("not-a-uuid"))
+	a.True(RegexArg(`^4\d$`).Match(42), "non-string values must be formatted")
+	a.Panics(func() { RegexArg(`(`) })
+
+	mock.ExpectExec("INSERT INTO users").
+		WithArgs(uuid, RegexArg(`^[^@]+@example\.com$`)).
+		WillReturnResult(NewResult("INSERT", 1))
+	_, err := mock.Exec(context.Background(), "INSERT INTO users(id, email) VALUES ($1, $2)", "7d444840-9dc0-11d1-b245-5ffdce74fad2", "john@example.org")
+	a.Error(err)
+	_, err = mock.Exec(context.Background(), "INSERT INTO users(id, email) VALUES ($1, $2)", "7d444840-9dc0-11d1-b245-5ffdce74fad2", "john@example.com")
+	a.NoError(err)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestTimeWithinArgument(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	now := time.Now()
+	arg := TimeWithinArg(now, time.Second)
+	later := now.Add(500 * time.Millisecond)
+
+	a.True(arg.Match(later))
+	a.True(arg.Match(&later))
+	a.True(arg.Match(now.Add(-time.Second)))
+	a.False(arg.Match(now.Add(2 * time.Second)))
+	a.True(arg.Match(pgtype.Timestamptz{Time: later, Valid: true}))
+	a.True(arg.Match(pgtype.Timestamp{Time: later, Valid: true}))
+	a.False(arg.Match(pgtype.Timestamptz{}), "NULL must not match")
+	a.False(arg.Match((*time.Time)(nil)))
+	a.False(arg.Match(later.String()))
+
+	mock, _ := NewConn()
+	mock.ExpectExec("UPDATE users SET updated_at").
+		WithArgs(TimeWithinArg(time.Now(), time.Minute)).
+		WillReturnResult(NewResult("UPDATE", 1))
+	_, err := mock.Exec(context.Background(), "UPDATE users SET updated_at = $1", time.Now())
+	a.NoError(err)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestJSONArgument(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	type user struct {
+		Name  string   `json:"name"`
+		Roles []string `json:"roles"`
+	}
+	arg := JSONArg(`{"name": "john", "roles": ["admin"]}`)
+
+	a.True(arg.Match([]byte(`{"roles":["admin"],"name":"john"}`)))
+	a.True(arg.Match(user{Name: "john", Roles: []string{"admin"}}))
+	a.True(arg.Match(map[string]any{"roles": []string{"admin"}, "name": "john"}))
+	a.False(arg.Match(`{"name": "john", "roles": []}`))
+	a.False(arg.Match([]byte(`{"name": "john"`)), "invalid JSON must not match")
+	a.False(arg.Match(nil))
+	a.True(JSONArg(user{Name: "john"}).Match(`{"name":"john","roles":null}`))
+	a.Panics(func() { JSONArg("{") })
+
+	mock, _ := NewConn()
+	mock.ExpectExec("UPDATE users SET profile").
+		WithArgs(JSONArg(map[string]any{"name": "john", "roles": []string{"admin"}})).
+		WillReturnResult(NewResult("UPDATE", 1))
+	_, err := mock.Exec(context.Background(), "UPDATE users SET profile = $1", user{Name: "john", Roles: []string{"admin"}})
+	a.NoError(err)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestNotNilArgument(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	arg := NotNilArg()
+	id := 42
+	a.True(arg.Match(0))
+	a.True(arg.Match(""))
+	a.True(arg.Match(&id))
+	a.True(arg.Match([]byte{}))
+	a.True(arg.Match(pgtype.Int8{Int64: 0, Valid: true}))
+	a.False(arg.Match(nil))
+	a.False(arg.Match((*int)(nil)))
+	a.False(arg.Match([]byte(nil)))
+	a.False(arg.Match(map[string]any(nil)))
+	a.False(arg.Match(pgtype.Int8{}), "NULL value of driver.Valuer must not match")
+
+	mock, _ := NewConn()
+	mock.ExpectExec("INSERT INTO orders").
+		WithArgs(NotNilArg(), AnyArg()).
+		WillReturnResult(NewResult("INSERT", 1))
+	_, err := mock.Exec(context.Background(), "INSERT INTO orders (user_id, note) VALUES ($1, $2)", (*int)(nil), nil)
+	a.Error(err)
+	_, err = mock.Exec(context.Background(), "INSERT INTO orders (user_id, note) VALUES ($1, $2)", &id, nil)
+	a.NoError(err)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestBytesArgument(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	hash := sha256.Sum256([]byte("secret"))
+	arg := BytesArg(hex.EncodeToString(hash[:]))
+	a.True(arg.Match(hash[:]))
+	a.True(arg.Match(hash), "byte arrays must match")
+	a.False(arg.Match(hex.EncodeToString(hash[:])), "strings must not match")
+	a.False(arg.Match([]byte(nil)))
+	a.True(Base64Arg("AQID").Match([]byte{1, 2, 3}))
+	a.False(Base64Arg("AQID").Match([]byte{1, 2}))
+	a.Equal("base64 'AQID'", fmt.Sprint(Base64Arg("AQID")))
+	a.Panics(func() { BytesArg("xyz") })
+	a.Panics(func() { Base64Arg("!") })
+
+	mock, _ := NewConn()
+	mock.ExpectExec("INSERT INTO tokens").
+		WithArgs(BytesArg("0a0b")).
+		WillReturnResult(NewResult("INSERT", 1))
+	_, err := mock.Exec(context.Background(), "INSERT INTO tokens (hash) VALUES ($1)", []byte{0x0a, 0x0c})
+	a.ErrorContains(err, "could not match 0 argument: expected hex '0a0b', but got '0a0c'")
+	_, err = mock.Exec(context.Background(), "INSERT INTO tokens (hash) VALUES ($1)", 42)
+	a.ErrorContains(err, "could not match 0 argument: expected hex '0a0b', but got int - 42")
+	_, err = mock.Exec(context.Background(), "INSERT INTO tokens (hash) VALUES ($1)", []byte{0x0a, 0x0b})
+	a.NoError(err)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+// message mimics a generated protobuf message
+type message struct {
+	ID   int
+	Tags []string
+}
+
+func unmarshalMessage(b []byte, m *message) error { return json.Unmarshal(b, m) }
+
+func equalMessages(x, y *message) bool { return reflect.DeepEqual(x, y) }
+
+func TestProtoArgument(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	arg := ProtoArg(&message{ID: 1, Tags: []string{"a"}}, unmarshalMessage, equalMessages)
+	a.True(arg.Match([]byte(`{"Tags":["a"],"ID":1}`)), "field order must not matter")
+	a.False(arg.Match([]byte(`{"ID":2}`)))
+	a.False(arg.Match([]byte(`garbage`)))
+	a.False(arg.Match(`{"ID":1,"Tags":["a"]}`), "only []byte must match")
+	a.False(ProtoArg(message{}, func([]byte, message) error { return nil },
+		func(message, message) bool { return true }).Match([]byte{}), "messages must be pointers")
+
+	mock, _ := NewConn()
+	mock.ExpectExec("INSERT INTO events").
+		WithArgs(ProtoArg(&message{ID: 1}, unmarshalMessage, equalMessages)).
+		WillReturnResult(NewResult("INSERT", 1))
+	_, err := mock.Exec(context.Background(), "INSERT INTO events (payload) VALUES ($1)", []byte(`{"ID":2}`))
+	a.ErrorContains(err, "expected message &{ID:1 Tags:[]}, but got &{ID:2 Tags:[]}")
+	_, err = mock.Exec(context.Background(), "INSERT INTO events (payload) VALUES ($1)", []byte(`{"ID":1}`))
+	a.NoError(err)
+	a.NoError(mock.ExpectationsWereMet())
+}
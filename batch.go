@@ -0,0 +1,430 @@
+package pgxmock
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	pgx "github.com/jackc/pgx/v5"
+	pgconn "github.com/jackc/pgx/v5/pgconn"
+)
+
+// BatchElement is an expected query queued in the pgx.Batch.
+// Returned by pgxmock.NewBatchElement.
+type BatchElement struct {
+	queryBasedExpectation
+	rows     []*Rows
+	result   pgconn.CommandTag
+	err      error
+	optional bool
+}
+
+// NewBatchElement creates a new expected query with arguments queued in the batch.
+// For specific arguments an pgxmock.Argument interface can be used to match an argument.
+func NewBatchElement(sql string, args ...interface{}) *BatchElement {
+	be := &BatchElement{}
+	be.expectSQL = sql
+	be.args = args
+	return be
+}
+
+// WillReturnRows specifies the set of resulting rows that will be returned
+// by the BatchResults.Query() or BatchResults.QueryRow() for this element
+func (be *BatchElement) WillReturnRows(rows ...*Rows) *BatchElement {
+	be.rows = rows
+	return be
+}
+
+// WillReturnResult arranges for a BatchResults.Exec() to return a particular
+// result for this element
+func (be *BatchElement) WillReturnResult(result pgconn.CommandTag) *BatchElement {
+	be.result = result
+	return be
+}
+
+// WillReturnError allows to set an error returned while reading the results of this element
+func (be *BatchElement) WillReturnError(err error) *BatchElement {
+	be.err = err
+	return be
+}
+
+// Maybe allows the element to be missing from the batch, e.g. when
+// the query is queued only under certain conditions
+func (be *BatchElement) Maybe() *BatchElement {
+	be.optional = true
+	return be
+}
+
+// String returns string representation
+func (be *BatchElement) String() string {
+	msg := fmt.Sprintf("matches sql (%s): '%s'", matcherName(be.matcher()), be.expectSQL)
+	if len(be.args) > 0 {
+		msg += fmt.Sprintf(" with arguments: %+v", be.args)
+	}
+	if be.err != nil {
+		msg += fmt.Sprintf(", returns error: %s", be.err)
+	}
+	if be.optional {
+		msg += ", optional"
+	}
+	return msg
+}
+
+// Batch is an expected pgx.Batch with queued queries.
+// Returned by pgxmock.NewBatch.
+type Batch struct {
+	elements []*BatchElement
+}
+
+// NewBatch creates a new expected batch
+func NewBatch() *Batch {
+	return &Batch{}
+}
+
+// AddBatchElements adds expected queries to the batch in the order
+// they are expected to be queued
+func (b *Batch) AddBatchElements(elements ...*BatchElement) *Batch {
+	b.elements = append(b.elements, elements...)
+	return b
+}
+
+// ExpectedBatch is used to manage pgx.SendBatch expectations.
+// Returned by pgxmock.ExpectSendBatch.
+type ExpectedBatch struct {
+	commonExpectation
+	batch          *Batch
+	expectPrepares bool
+	prepares       []string
+	lenOnly        bool // only the number of queued queries is checked
+	expectedLen    int
+	unordered      bool     // queued queries may match elements in any order
+	captures       []func() // captures of the matched elements stored once fulfilled
+}
+
+// ExpectsPrepares arranges to track statements which are prepared by pgx
+// before the batch is sent. The same as pgx does in the extended protocol
+// modes, e.g. the default QueryExecModeCacheStatement, every distinct
+// statement of the batch is prepared unless it is a name of an already
+// prepared statement. Statements are not prepared in QueryExecModeExec and
+// QueryExecModeSimpleProtocol modes set by ConnConfigOption or per query.
+func (e *ExpectedBatch) ExpectsPrepares() *ExpectedBatch {
+	e.expectPrepares = true
+	return e
+}
+
+// Unordered allows queued queries to match expected batch elements in
+// any order, e.g. for batches built from maps. Every queued query must
+// still match a distinct element and every required element must be matched.
+func (e *ExpectedBatch) Unordered() *ExpectedBatch {
+	e.unordered = true
+	return e
+}
+
+// Prepares returns SQL statements prepared by the matched SendBatch() call
+// in the order they were queued
+func (e *ExpectedBatch) Prepares() []string {
+	e.Lock()
+	defer e.Unlock()
+	return e.prepares
+}
+
+// fulfill counts the call and stores captured arguments of the matched elements
+func (e *ExpectedBatch) fulfill() {
+	e.commonExpectation.fulfill()
+	for _, fn := range e.captures {
+		fn()
+	}
+	e.captures = nil
+}
+
+// String returns string representation
+func (e *ExpectedBatch) String() string {
+	w := new(strings.Builder)
+	w.WriteString("ExpectedBatch => expecting call to SendBatch():\n")
+	if e.lenOnly {
+		fmt.Fprintf(w, "\t- is with %d queued queries of any content\n", e.expectedLen)
+	} else if len(e.batch.elements) == 0 {
+		w.WriteString("\t- is without queued queries\n")
+	} else {
+		w.WriteString("\t- is with queued queries:\n")
+		for i, be := range e.batch.elements {
+			fmt.Fprintf(w, "\t\t%d - %s\n", i, be)
+		}
+	}
+	if e.unordered {
+		w.WriteString("\t- queries may be queued in any order\n")
+	}
+	if e.expectPrepares {
+		w.WriteString("\t- expects statements to be prepared\n")
+	}
+	return w.String() + e.commonExpectation.String()
+}
+
+// batchMatches checks whether the queued queries match expected batch elements
+// and returns elements matched in the order of queued queries
+func (e *ExpectedBatch) batchMatches(c *pgxmock, b *pgx.Batch) ([]*BatchElement, error) {
+	if b == nil {
+		return nil, errors.New("SendBatch: batch must not be nil")
+	}
+	if e.lenOnly {
+		if len(b.QueuedQueries) != e.expectedLen {
+			return nil, fmt.Errorf("SendBatch: expected %d, but got %d queued queries", e.expectedLen, len(b.QueuedQueries))
+		}
+		return nil, nil
+	}
+	var required int
+	for _, be := range e.batch.elements {
+		if !be.optional {
+			required++
+		}
+	}
+	if n := len(b.QueuedQueries); n < required || n > len(e.batch.elements) {
+		if required == len(e.batch.elements) {
+			return nil, fmt.Errorf("SendBatch: expected %d, but got %d queued queries", required, n)
+		}
+		return nil, fmt.Errorf("SendBatch: expected from %d to %d, but got %d queued queries", required, len(e.batch.elements), n)
+	}
+	var matched []*BatchElement
+	var err error
+	if e.unordered {
+		matched, err = matchElementsUnordered(c, b.QueuedQueries, e.batch.elements)
+	} else {
+		matched, err = matchElements(c, b.QueuedQueries, 0, e.batch.elements)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// elements may be tried against several queries, so captures
+	// are taken from the queries the elements were finally matched by
+	e.captures = nil
+	for i, be := range matched {
+		qq := b.QueuedQueries[i]
+		_ = c.queryMatches(&be.queryBasedExpectation, qq.SQL, qq.Arguments)
+		e.captures = append(e.captures, be.captures...)
+		be.captures = nil
+	}
+	if e.expectPrepares {
+		e.prepares = nil
+		for _, qq := range b.QueuedQueries {
+			if _, ok := c.preparedSQL(qq.SQL); ok {
+				continue
+			}
+			if mode := c.execMode(qq.Arguments); mode == pgx.QueryExecModeExec || mode == pgx.QueryExecModeSimpleProtocol {
+				continue
+			}
+			if !slices.Contains(e.prepares, qq.SQL) {
+				e.prepares = append(e.prepares, qq.SQL)
+			}
+		}
+	}
+	return matched, nil
+}
+
+// matchElements matches queued queries starting from the idx against
+// expected elements, optional elements are skipped if they don't match
+// or there are not enough queued queries left for them
+func matchElements(c *pgxmock, qqs []*pgx.QueuedQuery, idx int, elements []*BatchElement) ([]*BatchElement, error) {
+	if len(elements) == 0 {
+		if idx < len(qqs) {
+			return nil, fmt.Errorf("SendBatch: queued query %d was not expected", idx)
+		}
+		return nil, nil
+	}
+	be := elements[0]
+	var err error
+	if idx < len(qqs) {
+		qq := qqs[idx]
+		if err = c.queryMatches(&be.queryBasedExpectation, qq.SQL, qq.Arguments); err != nil {
+			err = fmt.Errorf("SendBatch: queued query %d does not match: %w", idx, err)
+		} else {
+			var matched []*BatchElement
+			if matched, err = matchElements(c, qqs, idx+1, elements[1:]); err == nil {
+				return append([]*BatchElement{be}, matched...), nil
+			}
+		}
+	} else if !be.optional {
+		err = fmt.Errorf("SendBatch: expected query was not queued: %s", be)
+	}
+	if !be.optional {
+		return nil, err
+	}
+	matched, skipErr := matchElements(c, qqs, idx, elements[1:])
+	if skipErr != nil && err != nil {
+		return nil, err
+	}
+	return matched, skipErr
+}
+
+// matchElementsUnordered finds one-to-one correspondence between queued
+// queries and expected elements, elements are returned in the order of queries
+func matchElementsUnordered(c *pgxmock, qqs []*pgx.QueuedQuery, elements []*BatchElement) ([]*BatchElement, error) {
+	matches := make([][]bool, len(qqs)) // whether query i matches element j
+	for i, qq := range qqs {
+		matches[i] = make([]bool, len(elements))
+		var matchesAny bool
+		for j, be := range elements {
+			matches[i][j] = c.queryMatches(&be.queryBasedExpectation, qq.SQL, qq.Arguments) == nil
+			matchesAny = matchesAny || matches[i][j]
+		}
+		if !matchesAny {
+			return nil, fmt.Errorf("SendBatch: queued query %d does not match any expected query: %s", i, qq.SQL)
+		}
+	}
+	used := make([]bool, len(elements))
+	matched := make([]*BatchElement, len(qqs))
+	var assign func(i int) bool
+	assign = func(i int) bool {
+		if i == len(qqs) {
+			for j, be := range elements {
+				if !used[j] && !be.optional {
+					return false
+				}
+			}
+			return true
+		}
+		for j := range elements {
+			if used[j] || !matches[i][j] {
+				continue
+			}
+			used[j], matched[i] = true, elements[j]
+			if assign(i + 1) {
+				return true
+			}
+			used[j] = false
+		}
+		return false
+	}
+	if !assign(0) {
+		return nil, errors.New("SendBatch: queued queries cannot be matched one-to-one with expected queries")
+	}
+	return matched, nil
+}
+
+// aliasedArgs reports map or slice arguments, e.g. pgx.NamedArgs, shared
+// by several queued queries. Since arguments are encoded only when the batch is
+// sent, mutations of the shared argument, e.g. in a loop, affect every query.
+func aliasedArgs(qqs []*pgx.QueuedQuery) (warnings []string) {
+	type ref struct {
+		typ reflect.Type
+		ptr uintptr
+	}
+	seen := make(map[ref]int)
+	for i, qq := range qqs {
+		for _, arg := range qq.Arguments {
+			v := reflect.ValueOf(arg)
+			switch v.Kind() {
+			case reflect.Map:
+			case reflect.Slice:
+				if v.Cap() == 0 {
+					continue
+				}
+			default:
+				continue
+			}
+			if v.IsNil() {
+				continue
+			}
+			r := ref{v.Type(), v.Pointer()}
+			j, ok := seen[r]
+			if !ok {
+				seen[r] = i
+			} else if j >= 0 && j != i {
+				warnings = append(warnings, fmt.Sprintf("queued queries %d and %d of the batch share the same %T argument, "+
+					"its mutations affect both queries, pass a copy to every query instead", j, i, arg))
+				seen[r] = -1 // report once
+			}
+		}
+	}
+	return warnings
+}
+
+// batchResults implements pgx.BatchResults returning results
+// of the expected batch elements one by one
+type batchResults struct {
+	batch    *pgx.Batch
+	ex       *ExpectedBatch
+	elements []*BatchElement // expected elements matched by queued queries
+	qqIdx    int
+	err      error
+	closed   bool
+}
+
+var errBatchClosed = errors.New("batch already closed")
+
+func (br *batchResults) nextElement() (*BatchElement, error) {
+	if br.err != nil {
+		return nil, br.err
+	}
+	if br.closed {
+		return nil, errBatchClosed
+	}
+	if br.ex.lenOnly && br.qqIdx < br.ex.expectedLen {
+		br.qqIdx++
+		return &BatchElement{}, nil
+	}
+	if br.qqIdx >= len(br.elements) {
+		return nil, errors.New("no more results in batch")
+	}
+	be := br.elements[br.qqIdx]
+	br.qqIdx++
+	return be, nil
+}
+
+// Exec reads the results from the next query in the batch
+func (br *batchResults) Exec() (pgconn.CommandTag, error) {
+	be, err := br.nextElement()
+	if err != nil {
+		return pgconn.NewCommandTag(""), err
+	}
+	return be.result, be.err
+}
+
+// Query reads the results from the next query in the batch
+func (br *batchResults) Query() (pgx.Rows, error) {
+	be, err := br.nextElement()
+	if err != nil {
+		return nil, err
+	}
+	sets := be.rows
+	if len(sets) == 0 {
+		sets = []*Rows{NewRows(nil)}
+	}
+	return &rowSets{sets: sets}, be.err
+}
+
+// QueryRow reads the results from the next query in the batch
+func (br *batchResults) QueryRow() pgx.Row {
+	rows, err := br.Query()
+	if err != nil {
+		return errRow{err}
+	}
+	_ = rows.Next()
+	return rows
+}
+
+// Close reads all unread results calling callback functions
+// registered with pgx.QueuedQuery if any
+func (br *batchResults) Close() error {
+	if br.closed {
+		return br.err
+	}
+	for br.err == nil && br.qqIdx < len(br.batch.QueuedQueries) {
+		var err error
+		idx := br.qqIdx
+		if fn := br.batch.QueuedQueries[idx].Fn; fn != nil {
+			err = fn(br)
+		} else {
+			_, err = br.Exec()
+		}
+		if err != nil {
+			br.err = err
+		}
+		if br.qqIdx == idx { // callback did not read the results
+			br.qqIdx++
+		}
+	}
+	br.closed = true
+	return br.err
+}
@@ -0,0 +1,392 @@
+package pgxmock
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	pgx "github.com/jackc/pgx/v5"
+	pgconn "github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendBatch(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+
+	mock.ExpectSendBatch(NewBatch().AddBatchElements(
+		NewBatchElement("INSERT INTO users", "John").WillReturnResult(NewResult("INSERT", 1)),
+		NewBatchElement("SELECT name FROM users WHERE id", 1).
+			WillReturnRows(NewRows([]string{"name"}).AddRow("John")),
+		NewBatchElement("SELECT count").
+			WillReturnRows(NewRows([]string{"count"}).AddRow(1)),
+	))
+
+	b := &pgx.Batch{}
+	b.Queue("INSERT INTO users(name) VALUES ($1)", "John")
+	b.Queue("SELECT name FROM users WHERE id = $1", 1)
+	var count int
+	b.Queue("SELECT count(*) FROM users").QueryRow(func(row pgx.Row) error {
+		return row.Scan(&count)
+	})
+
+	br := mock.SendBatch(ctx, b)
+	res, err := br.Exec()
+	a.NoError(err)
+	a.True(res.Insert())
+	var name string
+	a.NoError(br.QueryRow().Scan(&name))
+	a.Equal("John", name)
+	a.NoError(br.Close(), "Close() must read the rest of the results")
+	a.Equal(1, count)
+	a.NoError(br.Close(), "Close() is safe to call multiple times")
+	_, err = br.Exec()
+	a.ErrorIs(err, errBatchClosed)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestSendBatchErrors(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+
+	br := mock.SendBatch(ctx, &pgx.Batch{})
+	_, err := br.Exec()
+	a.Error(err, "SendBatch() was not expected")
+	a.Error(br.Close())
+
+	mock.ExpectSendBatch(NewBatch().AddBatchElements(NewBatchElement("SELECT 1")))
+	b := &pgx.Batch{}
+	b.Queue("SELECT 1")
+	b.Queue("SELECT 2")
+	br = mock.SendBatch(ctx, b)
+	_, err = br.Query()
+	a.ErrorContains(err, "expected 1, but got 2 queued queries")
+	a.Error(mock.ExpectationsWereMet())
+
+	b = &pgx.Batch{}
+	b.Queue("SELECT 2")
+	a.ErrorContains(mock.SendBatch(ctx, b).Close(), "queued query 0 does not match")
+	b = &pgx.Batch{}
+	b.Queue("SELECT 1")
+	a.NoError(mock.SendBatch(ctx, b).Close())
+
+	errElem := errors.New("element error")
+	mock.ExpectSendBatch(NewBatch().AddBatchElements(
+		NewBatchElement("SELECT 1").WillReturnError(errElem),
+	))
+	b = &pgx.Batch{}
+	b.Queue("SELECT 1")
+	br = mock.SendBatch(ctx, b)
+	a.ErrorIs(br.QueryRow().Scan(), errElem)
+	_, err = br.Exec()
+	a.Error(err, "no more results in batch")
+	a.NoError(br.Close())
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestSendBatchExpectsPrepares(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+
+	mock.ExpectPrepare("getUser", "SELECT name FROM users")
+	eb := mock.ExpectSendBatch(NewBatch().AddBatchElements(
+		NewBatchElement("INSERT", 1).WillReturnResult(NewResult("INSERT", 1)),
+		NewBatchElement("INSERT", 2).WillReturnResult(NewResult("INSERT", 1)),
+		NewBatchElement("getUser", 1).WillReturnRows(NewRows([]string{"name"})),
+		NewBatchElement("DELETE").WillReturnResult(NewResult("DELETE", 2)),
+	)).ExpectsPrepares()
+	a.Contains(eb.String(), "expects statements to be prepared")
+
+	_, err := mock.Prepare(ctx, "getUser", "SELECT name FROM users WHERE id = $1")
+	a.NoError(err)
+	b := &pgx.Batch{}
+	b.Queue("INSERT INTO users(id) VALUES ($1)", 1)
+	b.Queue("INSERT INTO users(id) VALUES ($1)", 2)
+	b.Queue("getUser", 1)
+	b.Queue("DELETE FROM users")
+	a.NoError(mock.SendBatch(ctx, b).Close())
+	a.Equal([]string{"INSERT INTO users(id) VALUES ($1)", "DELETE FROM users"}, eb.Prepares())
+	a.NoError(mock.ExpectationsWereMet())
+
+	b = &pgx.Batch{}
+	b.Queue("INSERT INTO users(id) VALUES ($1)", pgx.QueryExecModeExec, 3)
+	b.Queue("DELETE FROM users", pgx.QueryExecModeCacheDescribe)
+	eb = mock.ExpectSendBatch(NewBatch().AddBatchElements(
+		NewBatchElement("INSERT", pgx.QueryExecModeExec, 3),
+		NewBatchElement("DELETE", pgx.QueryExecModeCacheDescribe),
+	)).ExpectsPrepares()
+	a.NoError(mock.SendBatch(ctx, b).Close())
+	a.Equal([]string{"DELETE FROM users"}, eb.Prepares(), "per query exec mode must be respected")
+
+	for _, mode := range []pgx.QueryExecMode{pgx.QueryExecModeExec, pgx.QueryExecModeSimpleProtocol} {
+		mock, _ := NewConn(ConnConfigOption(&pgx.ConnConfig{DefaultQueryExecMode: mode}))
+		eb := mock.ExpectSendBatch(NewBatch().AddBatchElements(
+			NewBatchElement("INSERT", 1),
+			NewBatchElement("DELETE"),
+		)).ExpectsPrepares()
+		b := &pgx.Batch{}
+		b.Queue("INSERT INTO users(id) VALUES ($1)", 1)
+		b.Queue("DELETE FROM users")
+		a.NoError(mock.SendBatch(ctx, b).Close())
+		a.Empty(eb.Prepares(), "no statements are prepared in %s mode", mode)
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestSendBatchLen(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+
+	eb := mock.ExpectSendBatchLen(2)
+	a.Contains(eb.String(), "is with 2 queued queries of any content")
+
+	b := &pgx.Batch{}
+	b.Queue("INSERT INTO foo VALUES ($1)", 1)
+	a.ErrorContains(mock.SendBatch(ctx, b).Close(), "expected 2, but got 1 queued queries")
+
+	b.Queue("DELETE FROM bar")
+	br := mock.SendBatch(ctx, b)
+	res, err := br.Exec()
+	a.NoError(err)
+	a.Zero(res.RowsAffected())
+	rows, err := br.Query()
+	a.NoError(err)
+	a.False(rows.Next(), "results of queries are empty")
+	_, err = br.Exec()
+	a.Error(err, "no more results in batch")
+	a.NoError(br.Close())
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestSendBatchOptionalElements(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+	mock.MatchExpectationsInOrder(false)
+
+	expectBatch := func() {
+		mock.ExpectSendBatch(NewBatch().AddBatchElements(
+			NewBatchElement("INSERT INTO orders").WillReturnResult(NewResult("INSERT", 1)),
+			NewBatchElement("INSERT INTO audit").WillReturnResult(NewResult("INSERT", 2)).Maybe(),
+			NewBatchElement("UPDATE stats").WillReturnResult(NewResult("UPDATE", 3)),
+		))
+	}
+	queue := func(sql ...string) *pgx.Batch {
+		b := &pgx.Batch{}
+		for _, s := range sql {
+			b.Queue(s)
+		}
+		return b
+	}
+
+	expectBatch()
+	br := mock.SendBatch(ctx, queue("INSERT INTO orders", "INSERT INTO audit", "UPDATE stats"))
+	for _, expected := range []int64{1, 2, 3} {
+		res, err := br.Exec()
+		a.NoError(err)
+		a.Equal(expected, res.RowsAffected())
+	}
+	a.NoError(br.Close())
+
+	expectBatch()
+	br = mock.SendBatch(ctx, queue("INSERT INTO orders", "UPDATE stats"))
+	for _, expected := range []int64{1, 3} {
+		res, err := br.Exec()
+		a.NoError(err)
+		a.Equal(expected, res.RowsAffected(), "optional element must be skipped")
+	}
+	a.NoError(br.Close())
+	a.NoError(mock.ExpectationsWereMet())
+
+	expectBatch()
+	a.ErrorContains(mock.SendBatch(ctx, queue("INSERT INTO orders")).Close(), "expected from 2 to 3, but got 1 queued queries")
+	a.ErrorContains(mock.SendBatch(ctx, queue("INSERT INTO orders", "DELETE FROM audit")).Close(), "queued query 1 does not match")
+	a.Error(mock.ExpectationsWereMet())
+}
+
+func TestSendBatchInOrder(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+	expect := func() {
+		mock.ExpectQuery("SELECT balance").WillReturnRows(NewRows([]string{"balance"}).AddRow(100))
+		mock.ExpectSendBatch(NewBatch().AddBatchElements(
+			NewBatchElement("UPDATE accounts").WillReturnResult(NewResult("UPDATE", 1)),
+		))
+		mock.ExpectExec("INSERT INTO audit").WillReturnResult(NewResult("INSERT", 1))
+	}
+	b := &pgx.Batch{}
+	b.Queue("UPDATE accounts SET balance = 0")
+
+	expect()
+	rows, err := mock.Query(ctx, "SELECT balance FROM accounts")
+	a.NoError(err)
+	rows.Close()
+	a.NoError(mock.SendBatch(ctx, b).Close())
+	_, err = mock.Exec(ctx, "INSERT INTO audit VALUES (1)")
+	a.NoError(err)
+	a.NoError(mock.ExpectationsWereMet())
+
+	expect()
+	a.ErrorContains(mock.SendBatch(ctx, b).Close(), "call to method SendBatch(), was not expected, next expectation is: ExpectedQuery")
+	rows, err = mock.Query(ctx, "SELECT balance FROM accounts")
+	a.NoError(err)
+	rows.Close()
+	_, err = mock.Exec(ctx, "INSERT INTO audit VALUES (1)")
+	a.ErrorContains(err, "call to method Exec(), was not expected, next expectation is: ExpectedBatch")
+	a.NoError(mock.SendBatch(ctx, b).Close())
+	_, err = mock.Exec(ctx, "INSERT INTO audit VALUES (1)")
+	a.NoError(err)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestSendBatchUnordered(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+	expect := func() {
+		mock.ExpectSendBatch(NewBatch().AddBatchElements(
+			NewBatchElement("UPDATE users", 1).WillReturnResult(NewResult("UPDATE", 1)),
+			NewBatchElement("UPDATE users", AnyArg()).WillReturnResult(NewResult("UPDATE", 2)),
+			NewBatchElement("DELETE FROM cache").WillReturnResult(NewResult("DELETE", 3)).Maybe(),
+		)).Unordered()
+	}
+
+	expect()
+	b := &pgx.Batch{}
+	b.Queue("UPDATE users SET active = true WHERE id = $1", 2)
+	b.Queue("UPDATE users SET active = true WHERE id = $1", 1)
+	br := mock.SendBatch(ctx, b)
+	tag, err := br.Exec()
+	a.NoError(err)
+	a.Equal("UPDATE 2", tag.String(), "the first query must not take the element of the second one")
+	tag, err = br.Exec()
+	a.NoError(err)
+	a.Equal("UPDATE 1", tag.String())
+	a.NoError(br.Close())
+
+	expect()
+	b = &pgx.Batch{}
+	b.Queue("UPDATE users SET active = true WHERE id = $1", 2)
+	b.Queue("UPDATE users SET active = true WHERE id = $1", 3)
+	a.ErrorContains(mock.SendBatch(ctx, b).Close(), "cannot be matched one-to-one")
+	b = &pgx.Batch{}
+	b.Queue("INSERT INTO users VALUES ($1)", 2)
+	b.Queue("UPDATE users SET active = true WHERE id = $1", 3)
+	a.ErrorContains(mock.SendBatch(ctx, b).Close(), "queued query 0 does not match any expected query")
+	b = &pgx.Batch{}
+	b.Queue("DELETE FROM cache")
+	b.Queue("UPDATE users SET active = true WHERE id = $1", 1)
+	b.Queue("UPDATE users SET active = true WHERE id = $1", 1)
+	a.NoError(mock.SendBatch(ctx, b).Close())
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestSendBatchAliasedArgs(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	var warnings []string
+	mock, _ := NewConn(WarningHandler(func(msg string) { warnings = append(warnings, msg) }))
+	mock.ExpectSendBatchLen(3).Times(3)
+
+	b := &pgx.Batch{}
+	args := pgx.NamedArgs{}
+	for _, name := range []string{"john", "mary", "bob"} {
+		args["name"] = name
+		b.Queue("INSERT INTO users (name) VALUES (@name)", args)
+	}
+	a.NoError(mock.SendBatch(ctx, b).Close())
+	a.Equal([]string{"queued queries 0 and 1 of the batch share the same pgx.NamedArgs argument, " +
+		"its mutations affect both queries, pass a copy to every query instead"}, warnings)
+
+	warnings = nil
+	b = &pgx.Batch{}
+	for _, name := range []string{"john", "mary", "bob"} {
+		b.Queue("INSERT INTO users (name) VALUES (@name)", pgx.NamedArgs{"name": name}, []byte{}, name)
+	}
+	a.NoError(mock.SendBatch(ctx, b).Close())
+	a.Empty(warnings)
+
+	b = &pgx.Batch{}
+	tenant := new(int)
+	for _, name := range []string{"john", "mary", "bob"} {
+		b.Queue("INSERT INTO users (tenant, name) VALUES ($1, $2)", tenant, name)
+	}
+	a.NoError(mock.SendBatch(ctx, b).Close())
+	a.Empty(warnings)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestUnorderedBatches(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	mock, _ := NewPool(UnorderedBatches(true))
+	const n = 10
+	for i := 0; i < n; i++ {
+		mock.ExpectSendBatch(NewBatch().AddBatchElements(
+			NewBatchElement("INSERT INTO chunks", i).WillReturnResult(NewResult("INSERT", int64(i))),
+		))
+	}
+	mock.ExpectPing()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := n - 1; i >= 0; i-- {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			b := &pgx.Batch{}
+			b.Queue("INSERT INTO chunks VALUES ($1)", i)
+			br := mock.SendBatch(ctx, b)
+			defer br.Close()
+			tag, err := br.Exec()
+			if err == nil && tag.RowsAffected() != int64(i) {
+				err = fmt.Errorf("batch %d got results of batch %d", i, tag.RowsAffected())
+			}
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		a.NoError(err)
+	}
+	a.NoError(mock.Ping(ctx))
+	a.NoError(mock.ExpectationsWereMet())
+
+	mock, _ = NewPool()
+	mock.ExpectSendBatch(NewBatch().AddBatchElements(NewBatchElement("INSERT INTO chunks", 0)))
+	mock.ExpectSendBatch(NewBatch().AddBatchElements(NewBatchElement("INSERT INTO chunks", 1)))
+	b := &pgx.Batch{}
+	b.Queue("INSERT INTO chunks VALUES ($1)", 1)
+	a.Error(mock.SendBatch(ctx, b).Close(), "batches must be matched in order by default")
+}
+
+func ExampleExpectedBatch() {
+	mock, _ := NewConn()
+	eb := mock.ExpectSendBatch(NewBatch().AddBatchElements(
+		NewBatchElement("INSERT", 1).WillReturnResult(NewResult("INSERT", 1)),
+		NewBatchElement("SELECT").WillReturnError(pgx.ErrNoRows),
+	))
+	fmt.Print(eb)
+
+	b := &pgx.Batch{}
+	b.Queue("INSERT INTO users(id) VALUES ($1)", 1).Exec(func(ct pgconn.CommandTag) error {
+		fmt.Println(ct)
+		return nil
+	})
+	b.Queue("SELECT name FROM users")
+	fmt.Println(mock.SendBatch(ctx, b).Close())
+	// Output:
+	// ExpectedBatch => expecting call to SendBatch():
+	// 	- is with queued queries:
+	// 		0 - matches sql (regexp): 'INSERT' with arguments: [1]
+	// 		1 - matches sql (regexp): 'SELECT', returns error: no rows in result set
+	// INSERT 1
+	// no rows in result set
+}
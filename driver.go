@@ -3,13 +3,25 @@ package pgxmock
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
 
 	pgx "github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+var (
+	_ PgxConnIface = (*pgxmockConn)(nil)
+	_ PgxPoolIface = (*pgxmockPool)(nil)
+
+	_ PgxPoolConnIface = (*pgxmockConn)(nil)
+)
+
 type pgxmockConn struct {
 	pgxmock
+	released *atomic.Bool // set for connections acquired from the pool
 }
 
 // NewConn creates PgxConnIface database connection and a mock to manage expectations.
@@ -20,12 +32,42 @@ func NewConn(options ...func(*pgxmock) error) (PgxConnIface, error) {
 	return smock, smock.open(options)
 }
 
+// NewConnT creates PgxConnIface the same as NewConn, but fails the test
+// if options are invalid and checks whether all expectations were met
+// once the test and all its subtests complete, see Check
+func NewConnT(t testing.TB, options ...func(*pgxmock) error) PgxConnIface {
+	t.Helper()
+	mock, err := NewConn(append([]func(*pgxmock) error{logWarnings(t)}, options...)...)
+	if err != nil {
+		t.Fatalf("failed to create pgxmock connection: %s", err)
+	}
+	t.Cleanup(func() { mock.Check(t) })
+	return mock
+}
+
+// logWarnings is the default WarningHandler of mocks created for the test
+func logWarnings(t testing.TB) func(*pgxmock) error {
+	return WarningHandler(func(msg string) {
+		t.Helper()
+		t.Logf("pgxmock: warning: %s", msg)
+	})
+}
+
+func (c *pgxmockConn) Clone() (PgxConnIface, error) {
+	return &pgxmockConn{pgxmock: c.clone()}, nil
+}
+
+// Config returns a copy of the configuration set by ConnConfigOption
 func (c *pgxmockConn) Config() *pgx.ConnConfig {
-	return &pgx.ConnConfig{}
+	if c.connConfig == nil {
+		return &pgx.ConnConfig{}
+	}
+	return c.connConfig.Copy()
 }
 
 type pgxmockPool struct {
 	pgxmock
+	closing sync.Mutex // serializes Close() to count repeated calls
 }
 
 // NewPool creates PgxPoolIface pool of database connections and a mock to manage expectations.
@@ -36,21 +78,96 @@ func NewPool(options ...func(*pgxmock) error) (PgxPoolIface, error) {
 	return smock, smock.open(options)
 }
 
+// NewPoolT creates PgxPoolIface the same as NewPool, but fails the test
+// if options are invalid and checks whether all expectations were met
+// once the test and all its subtests complete, see Check
+func NewPoolT(t testing.TB, options ...func(*pgxmock) error) PgxPoolIface {
+	t.Helper()
+	mock, err := NewPool(append([]func(*pgxmock) error{logWarnings(t)}, options...)...)
+	if err != nil {
+		t.Fatalf("failed to create pgxmock pool: %s", err)
+	}
+	t.Cleanup(func() { mock.Check(t) })
+	return mock
+}
+
+func (p *pgxmockPool) Clone() (PgxPoolIface, error) {
+	return &pgxmockPool{pgxmock: p.clone()}, nil
+}
+
 func (p *pgxmockPool) Close() {
+	p.closing.Lock()
+	defer p.closing.Unlock()
+	if p.IsClosed() {
+		p.closedAgain()
+		return
+	}
 	p.pgxmock.Close(context.Background())
 }
 
-func (p *pgxmockPool) Acquire(context.Context) (*pgxpool.Conn, error) {
-	return nil, errors.New("pgpool.Acquire() method is not implemented")
+func (p *pgxmockPool) ExpectPoolClose() *ExpectedClose {
+	e := p.ExpectClose()
+	e.once = true
+	return e
+}
+
+// closedAgain counts Close() of the already closed pool against
+// the ExpectPoolClose expectation it was closed by, if any
+func (p *pgxmockPool) closedAgain() {
+	for _, e := range p.expectations {
+		ex, ok := e.(*ExpectedClose)
+		if !ok {
+			continue
+		}
+		ex.Lock()
+		closed := ex.once && ex.triggered > 0
+		if closed {
+			ex.repeated++
+		}
+		ex.Unlock()
+		if closed {
+			return
+		}
+	}
+}
+
+// Acquire returns the error planned by ExpectAcquire after the delay,
+// since *pgxpool.Conn cannot be mocked, use AsConn() instead
+func (p *pgxmockPool) Acquire(ctx context.Context) (*pgxpool.Conn, error) {
+	ex, err := findExpectation[*ExpectedAcquire](&p.pgxmock, "Acquire()")
+	if err != nil {
+		return nil, err
+	}
+	if err = ex.waitForDelay(ctx); err != nil {
+		return nil, err
+	}
+	return nil, errors.New("pgpool.Acquire() method is not implemented, only errors may be returned")
 }
 
 func (p *pgxmockPool) Config() *pgxpool.Config {
 	return &pgxpool.Config{}
 }
 
-// AsConn is similar to Acquire but returns proper mocking interface
-func (p *pgxmockPool) AsConn() PgxConnIface {
-	return &pgxmockConn{pgxmock: p.pgxmock}
+// AsConn is similar to Acquire but returns proper mocking interface,
+// the connection is counted as acquired until Release() is called
+func (p *pgxmockPool) AsConn() PgxPoolConnIface {
+	p.acquired.Add(1)
+	return &pgxmockConn{pgxmock: p.pgxmock, released: &atomic.Bool{}}
+}
+
+// Release returns the connection acquired by AsConn() to the pool,
+// subsequent calls do nothing the same as for *pgxpool.Conn
+func (c *pgxmockConn) Release() {
+	if c.released != nil && c.released.CompareAndSwap(false, true) {
+		c.acquired.Add(-1)
+	}
+}
+
+func (p *pgxmockPool) AssertBalancedPool() error {
+	if n := p.acquired.Load(); n > 0 {
+		return fmt.Errorf("there are %d connections acquired but not released, expected Release() to be called", n)
+	}
+	return nil
 }
 
 func (p *pgxmockPool) Stat() *pgxpool.Stat {
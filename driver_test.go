@@ -2,7 +2,16 @@ package pgxmock
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
 	"testing"
+	"time"
+
+	pgx "github.com/jackc/pgx/v5"
+	pgconn "github.com/jackc/pgx/v5/pgconn"
+	pgxpool "github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestTwoOpenConnectionsOnTheSameDSN(t *testing.T) {
@@ -52,6 +61,37 @@ func TestAcquire(t *testing.T) {
 	}
 }
 
+func TestExpectAcquire(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewPool()
+	a := assert.New(t)
+	ctx := context.Background()
+	mock.ExpectAcquire().WillTimeoutAcquire(10 * time.Millisecond)
+	mock.ExpectAcquire().WillTimeout(time.Millisecond)
+	mock.ExpectAcquire().WillReturnError(errors.New("pool closed"))
+	mock.ExpectAcquire()
+
+	start := time.Now()
+	_, err := mock.Acquire(ctx)
+	a.ErrorIs(err, context.DeadlineExceeded)
+	a.GreaterOrEqual(time.Since(start), 10*time.Millisecond)
+	_, err = mock.Acquire(ctx)
+	var pgErr *pgconn.PgError
+	a.ErrorAs(err, &pgErr, "WillTimeout behaves the same for every expectation")
+	a.Equal("57014", pgErr.Code)
+	_, err = mock.Acquire(ctx)
+	a.EqualError(err, "pool closed")
+	_, err = mock.Acquire(ctx)
+	a.ErrorContains(err, "not implemented")
+	a.NoError(mock.ExpectationsWereMet())
+
+	mock.ExpectAcquire().WillDelayFor(time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = mock.Acquire(ctx)
+	a.ErrorIs(err, context.DeadlineExceeded, "context deadline expected to be respected")
+}
+
 func TestPoolStat(t *testing.T) {
 	mock, err := NewPool()
 	if err != nil {
@@ -62,3 +102,244 @@ func TestPoolStat(t *testing.T) {
 		t.Error("expected stat object, but got nil")
 	}
 }
+
+// cleanupTB records errors and cleanup functions of the test
+type cleanupTB struct {
+	testing.TB
+	errors   []string
+	logs     []string
+	cleanups []func()
+}
+
+func (tb *cleanupTB) Logf(f string, args ...any) { tb.logs = append(tb.logs, fmt.Sprintf(f, args...)) }
+
+func (tb *cleanupTB) Helper()          {}
+func (tb *cleanupTB) Cleanup(f func()) { tb.cleanups = append(tb.cleanups, f) }
+func (tb *cleanupTB) Errorf(f string, args ...any) {
+	tb.errors = append(tb.errors, fmt.Sprintf(f, args...))
+}
+func (tb *cleanupTB) Fatalf(f string, args ...any) { tb.Errorf(f, args...) }
+
+func (tb *cleanupTB) cleanup() {
+	for _, f := range tb.cleanups {
+		f()
+	}
+}
+
+func TestNewConnT(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	tb := &cleanupTB{TB: t}
+	mock := NewConnT(tb)
+	mock.ExpectPing()
+	mock.ExpectExec("DELETE").WillReturnResult(NewResult("DELETE", 1))
+	a.NoError(mock.Ping(context.Background()))
+	tb.cleanup()
+	a.Len(tb.errors, 1)
+	a.Contains(tb.errors[0], "there were unfulfilled expectations")
+	a.Contains(tb.errors[0], "ExpectedExec => expecting call to Exec()")
+
+	tb = &cleanupTB{TB: t}
+	pool := NewPoolT(tb)
+	pool.ExpectPing()
+	a.NoError(pool.Ping(context.Background()))
+	tb.cleanup()
+	a.Empty(tb.errors)
+
+	tb = &cleanupTB{TB: t}
+	NewConnT(tb, func(*pgxmock) error { return errors.New("bad option") })
+	a.Equal([]string{"failed to create pgxmock connection: bad option"}, tb.errors)
+
+	NewConnT(t).ExpectPing().Maybe()
+
+	tb = &cleanupTB{TB: t}
+	pool = NewPoolT(tb)
+	pool.ExpectSendBatchLen(2)
+	b := &pgx.Batch{}
+	args := pgx.NamedArgs{"id": 1}
+	b.Queue("DELETE FROM users WHERE id = @id", args)
+	b.Queue("DELETE FROM orders WHERE user_id = @id", args)
+	a.NoError(pool.SendBatch(context.Background(), b).Close())
+	tb.cleanup()
+	a.Len(tb.logs, 1, "warnings must be logged by the test")
+	a.Contains(tb.logs[0], "pgxmock: warning: queued queries 0 and 1 of the batch share the same pgx.NamedArgs argument")
+}
+
+func TestClone(t *testing.T) {
+	mock, err := NewConn(QueryMatcherOption(QueryMatcherEqual), WithDefaultQueryTimeout(time.Minute))
+	a := assert.New(t)
+	a.NoError(err)
+	mock.MatchExpectationsInOrder(false)
+	mock.ExpectPing()
+
+	for _, name := range []string{"first", "second"} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			clone, err := mock.Clone()
+			a := assert.New(t)
+			a.NoError(err)
+			a.NoError(clone.ExpectationsWereMet(), "expectations must not be copied")
+			clone.ExpectExec("DELETE FROM foo").WillReturnResult(NewResult("DELETE", 1))
+			clone.ExpectQuery("SELECT (.+)").WillReturnRows(NewRows([]string{"id"}))
+			_, err = clone.Query(context.Background(), "SELECT (.+)")
+			a.NoError(err, "unordered equal matcher must be copied")
+			_, err = clone.Exec(context.Background(), "DELETE FROM foo")
+			a.NoError(err)
+			a.NoError(clone.ExpectationsWereMet())
+		})
+	}
+	a.Error(mock.ExpectationsWereMet(), "original expectations must stay intact")
+
+	pool, _ := NewPool()
+	pool.ExpectPing()
+	poolClone, err := pool.Clone()
+	a.NoError(err)
+	a.NoError(poolClone.ExpectationsWereMet())
+	a.Error(pool.ExpectationsWereMet())
+}
+
+// connIface is an example of the interface production code may accept
+type connIface interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+	Prepare(ctx context.Context, name, sql string) (*pgconn.StatementDescription, error)
+	Deallocate(ctx context.Context, name string) error
+	Ping(ctx context.Context) error
+	Close(ctx context.Context) error
+	IsClosed() bool
+	Config() *pgx.ConnConfig
+	PgConn() *pgconn.PgConn
+}
+
+// poolIface is an example of the interface production code may accept
+type poolIface interface {
+	Acquire(ctx context.Context) (*pgxpool.Conn, error)
+	AcquireAllIdle(ctx context.Context) []*pgxpool.Conn
+	AcquireFunc(ctx context.Context, f func(*pgxpool.Conn) error) error
+	Begin(ctx context.Context) (pgx.Tx, error)
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+	Ping(ctx context.Context) error
+	Close()
+	Reset()
+	Stat() *pgxpool.Stat
+	Config() *pgxpool.Config
+}
+
+var (
+	_ connIface = (*pgx.Conn)(nil)
+	_ connIface = PgxConnIface(nil)
+	_ poolIface = (*pgxpool.Pool)(nil)
+	_ poolIface = PgxPoolIface(nil)
+)
+
+func TestInterfacesAcceptMocks(t *testing.T) {
+	conn, _ := NewConn()
+	pool, _ := NewPool()
+	a := assert.New(t)
+	a.Implements((*connIface)(nil), conn)
+	a.Implements((*connIface)(nil), pool.AsConn())
+	a.Implements((*poolIface)(nil), pool)
+}
+
+func TestConnConfigOption(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	config, err := pgx.ParseConfig("postgres://bob@db.example.com:5433/orders")
+	a.NoError(err)
+	mock, err := NewConn(ConnConfigOption(config))
+	a.NoError(err)
+
+	c := mock.Config()
+	a.Equal("db.example.com", c.Host)
+	a.EqualValues(5433, c.Port)
+	a.Equal("orders", c.Database)
+	a.Equal("bob", c.User)
+	c.Database = "changed"
+	a.Equal("orders", mock.Config().Database, "a copy of config must be returned")
+
+	clone, _ := mock.Clone()
+	a.Equal("orders", clone.Config().Database)
+}
+
+func TestAssertBalancedPool(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	mock, err := NewPool()
+	a.NoError(err)
+	a.NoError(mock.AssertBalancedPool())
+
+	first, second := mock.AsConn(), mock.AsConn()
+	a.EqualError(mock.AssertBalancedPool(), "there are 2 connections acquired but not released, expected Release() to be called")
+	first.Release()
+	first.Release()
+	a.ErrorContains(mock.AssertBalancedPool(), "there are 1 connections", "repeated Release() must be ignored")
+	second.Release()
+	a.NoError(mock.AssertBalancedPool())
+
+	conn := mock.AsConn()
+	a.NotNil(conn.Config())
+	a.ErrorContains(mock.AssertBalancedPool(), "there are 1 connections", "AsConn() without Release() unbalances the pool")
+	conn.Release()
+	a.NoError(mock.AssertBalancedPool())
+
+	called := false
+	a.NoError(mock.AcquireFunc(ctx, func(*pgxpool.Conn) error { called = true; return nil }))
+	a.False(called, "*pgxpool.Conn can't be mocked")
+	a.NoError(mock.AssertBalancedPool(), "AcquireFunc() is not counted")
+
+	type pooledConn interface{ Release() }
+	notPooled, _ := NewConn()
+	notPooled.(pooledConn).Release() // not acquired from the pool
+	a.NoError(mock.AssertBalancedPool())
+}
+
+func TestExpectPoolClose(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	mock, _ := NewPool()
+	mock.ExpectPoolClose()
+	a.ErrorContains(mock.ExpectationsWereMet(), "expecting call to Close() of the pool exactly once")
+	mock.Close()
+	a.NoError(mock.ExpectationsWereMet())
+	mock.Close()
+	mock.Close()
+	a.ErrorContains(mock.ExpectationsWereMet(), "expected pool to be closed exactly once, but it was closed 3 times")
+
+	mock, _ = NewPool()
+	mock.ExpectClose()
+	mock.Close()
+	mock.Close()
+	a.NoError(mock.ExpectationsWereMet(), "repeated Close() is not tracked without ExpectPoolClose")
+}
+
+func TestExpectPoolCloseConcurrently(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	mock, _ := NewPool()
+	mock.ExpectPoolClose()
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			mock.Close()
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_ = mock.ExpectationsWereMet()
+	}()
+	wg.Wait()
+	a.ErrorContains(mock.ExpectationsWereMet(), "expected pool to be closed exactly once, but it was closed 2 times")
+}
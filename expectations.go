@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
 	"reflect"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
@@ -18,7 +20,14 @@ type expectation interface {
 	error() error
 	required() bool
 	fulfilled() bool
+	retriesFailed() bool
 	fulfill()
+	skip()
+	weight() int
+	caption() string
+	rationale() string
+	fallback() bool
+	attach(mock *pgxmock)
 	sync.Locker
 	fmt.Stringer
 }
@@ -26,7 +35,11 @@ type expectation interface {
 // CallModifier interface represents common interface for all expectations supported
 type CallModifier interface {
 	// Maybe allows the expected method call to be optional.
-	// Not calling an optional method will not cause an error while asserting expectations
+	// Not calling an optional method will not cause an error while asserting expectations.
+	// In order mode an optional expectation not matching the call is skipped,
+	// so the call may match the next expectations up to the first required one.
+	// Once the call matches, skipped expectations of the same method are not
+	// matched anymore, expectations of other methods are skipped for this call only.
 	Maybe() CallModifier
 	// Times indicates that that the expected method should only fire the indicated number of times.
 	// Zero value is ignored and means the same as one.
@@ -34,10 +47,38 @@ type CallModifier interface {
 	// WillDelayFor allows to specify duration for which it will delay
 	// result. May be used together with Context
 	WillDelayFor(duration time.Duration) CallModifier
+	// Priority allows to match the expectation before others regardless of
+	// the registration order. Higher priority expectations are matched first,
+	// the default priority is zero. In order mode the call is matched by the
+	// expectation with higher priority than the next one in order, if any,
+	// expectations not matching the call never block it because of the priority.
+	Priority(p int) CallModifier
+	// Label allows to specify a human-readable name of the expectation
+	// used in failure messages instead of the expectation details
+	Label(label string) CallModifier
+	// Because allows to specify the reason the call is expected, which is
+	// included in the failure message if the expectation is not met
+	Because(reason string) CallModifier
+	// Retryable allows the expected method call to be retried up to n times,
+	// so it matches n+1 calls at most. It is fulfilled once any of the calls
+	// succeeds, the next calls are not matched anymore. Attempts may fail
+	// with the context error, e.g. combined with WillDelayFor.
+	Retryable(n uint) CallModifier
 	// WillReturnError allows to set an error for the expected method
 	WillReturnError(err error)
+	// WillTimeout allows to simulate server side statement_timeout, the expected
+	// method will return query_canceled (57014) *pgconn.PgError after the delay
+	WillTimeout(after time.Duration) CallModifier
+	// WillReturnDeadlock allows to simulate a deadlock, the expected method
+	// will return deadlock_detected (40P01) *pgconn.PgError. To test retry
+	// loops, expect the call again for the retry after this expectation.
+	WillReturnDeadlock() CallModifier
 	// WillPanic allows to force the expected method to panic
 	WillPanic(v any)
+	// AndThen returns the mock the expectation belongs to, so the
+	// following expectations may be set fluently in order, e.g.
+	//	mock.ExpectBegin().AndThen().ExpectCommit()
+	AndThen() Expecter
 }
 
 // common expectation struct
@@ -50,6 +91,15 @@ type commonExpectation struct {
 	panicArgument any           // panic value to return for recovery
 	plannedDelay  time.Duration // should method delay before return
 	plannedCalls  uint          // how many sequentional calls should be made
+	priority      int           // higher priority expectations are matched first
+	label         string        // human-readable name for failure messages
+	because       string        // reason shown when the expectation is unmet
+	retryable     bool          // matches retries until a call succeeds, see Retryable
+	retries       uint          // how many times the call may be retried
+	succeeded     bool          // a call of the retryable expectation succeeded
+	catchAll      bool          // matches any call any number of times if nothing else matches
+	skipped       bool          // optional expectation passed over in order mode
+	mock          *pgxmock      // mock the expectation belongs to
 }
 
 func (e *commonExpectation) error() error {
@@ -61,19 +111,69 @@ func (e *commonExpectation) fulfill() {
 }
 
 func (e *commonExpectation) fulfilled() bool {
-	return e.triggered >= max(e.plannedCalls, 1)
+	if e.retryable {
+		return e.skipped || e.succeeded || e.triggered > e.retries
+	}
+	return e.skipped || !e.catchAll && e.triggered >= max(e.plannedCalls, 1)
+}
+
+// retriesFailed reports whether all attempts of the retryable expectation failed
+func (e *commonExpectation) retriesFailed() bool {
+	return e.retryable && !e.skipped && !e.succeeded && e.triggered > e.retries
+}
+
+// skip marks the optional expectation passed over by a call of the same
+// method matching one of the next expectations in order mode, so it is never matched
+func (e *commonExpectation) skip() {
+	e.skipped = !e.catchAll
 }
 
 func (e *commonExpectation) required() bool {
 	return !e.optional
 }
 
+func (e *commonExpectation) weight() int {
+	if e.catchAll {
+		return math.MinInt
+	}
+	return e.priority
+}
+
+func (e *commonExpectation) caption() string {
+	return e.label
+}
+
+func (e *commonExpectation) rationale() string {
+	return e.because
+}
+
+func (e *commonExpectation) fallback() bool {
+	return e.catchAll
+}
+
+func (e *commonExpectation) attach(mock *pgxmock) {
+	e.mock = mock
+}
+
+func (e *commonExpectation) AndThen() Expecter {
+	return e.mock
+}
+
+// waitForDelay returns the planned error after the delay or the context
+// error, e.g. context.Canceled, if the context is done before or meanwhile
 func (e *commonExpectation) waitForDelay(ctx context.Context) (err error) {
-	select {
-	case <-time.After(e.plannedDelay):
-		err = e.error()
-	case <-ctx.Done():
-		err = ctx.Err()
+	if err = ctx.Err(); err == nil {
+		select {
+		case <-time.After(e.plannedDelay):
+			err = e.error()
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+	if err == nil && e.retryable {
+		e.Lock()
+		e.succeeded = true
+		e.Unlock()
 	}
 	if e.panicArgument != nil {
 		panic(e.panicArgument)
@@ -81,6 +181,14 @@ func (e *commonExpectation) waitForDelay(ctx context.Context) (err error) {
 	return err
 }
 
+// MatchCount returns how many times the expectation was matched,
+// e.g. to check whether an optional expectation was exercised
+func (e *commonExpectation) MatchCount() int {
+	e.Lock()
+	defer e.Unlock()
+	return int(e.triggered)
+}
+
 func (e *commonExpectation) Maybe() CallModifier {
 	e.optional = true
 	return e
@@ -96,12 +204,51 @@ func (e *commonExpectation) WillDelayFor(duration time.Duration) CallModifier {
 	return e
 }
 
+func (e *commonExpectation) Priority(p int) CallModifier {
+	e.priority = p
+	return e
+}
+
+func (e *commonExpectation) Label(label string) CallModifier {
+	e.label = label
+	return e
+}
+
+func (e *commonExpectation) Because(reason string) CallModifier {
+	e.because = reason
+	return e
+}
+
+func (e *commonExpectation) Retryable(n uint) CallModifier {
+	e.retryable, e.retries = true, n
+	return e
+}
+
 func (e *commonExpectation) WillReturnError(err error) {
 	e.err = err
 }
 
+func (e *commonExpectation) WillTimeout(after time.Duration) CallModifier {
+	e.plannedDelay = after
+	e.err = &pgconn.PgError{
+		Severity: "ERROR",
+		Code:     "57014",
+		Message:  "canceling statement due to statement timeout",
+	}
+	return e
+}
+
 var errPanic = errors.New("pgxmock panic")
 
+func (e *commonExpectation) WillReturnDeadlock() CallModifier {
+	e.err = &pgconn.PgError{
+		Severity: "ERROR",
+		Code:     "40P01",
+		Message:  "deadlock detected",
+	}
+	return e
+}
+
 func (e *commonExpectation) WillPanic(v any) {
 	e.err = errPanic
 	e.panicArgument = v
@@ -110,6 +257,9 @@ func (e *commonExpectation) WillPanic(v any) {
 // String returns string representation
 func (e *commonExpectation) String() string {
 	w := new(strings.Builder)
+	if e.label != "" {
+		fmt.Fprintf(w, "\t- labeled as: '%s'\n", e.label)
+	}
 	if e.err != nil {
 		if e.err != errPanic {
 			fmt.Fprintf(w, "\t- returns error: %v\n", e.err)
@@ -126,61 +276,293 @@ func (e *commonExpectation) String() string {
 	if e.plannedCalls > 0 {
 		fmt.Fprintf(w, "\t- execution calls awaited: %d\n", e.plannedCalls)
 	}
+	if e.retryable {
+		fmt.Fprintf(w, "\t- may be retried up to %d times until succeeded\n", e.retries)
+	}
+	if e.priority != 0 {
+		fmt.Fprintf(w, "\t- matching priority: %d\n", e.priority)
+	}
+	if e.catchAll {
+		fmt.Fprint(w, "\t- matches any call not matched by other expectations\n")
+	}
 	return w.String()
 }
 
 // queryBasedExpectation is a base class that adds a query matching logic
 type queryBasedExpectation struct {
-	expectSQL          string
-	expectRewrittenSQL string
-	args               []interface{}
+	expectSQL           string
+	expectRewrittenSQL  string
+	expectRewrittenArgs []interface{}
+	expectArgsInOrder   []interface{} // rewritten args in the order of placeholders appearance
+	args                []interface{}
+	optionalArgs        bool // args are checked only if the call has any
+	queryMatcher        QueryMatcher
+	operation           string
+	role                string // database role the call is routed to, see ContextWithRole
+	contextMatcher      func(ctx context.Context) bool
+	checkPlaceholders   bool
+	placeholders        int  // expected number of placeholders
+	mustUsePrepared     bool // statement name must be passed instead of SQL
+	forbidden           []*regexp.Regexp
+	mustHaveLimit       bool     // SQL must end with LIMIT or FETCH FIRST clause
+	captures            []func() // captures of the last match stored once fulfilled
+}
+
+// reLimit matches the trailing row limiting clause with a value,
+// optionally followed by OFFSET and locking clauses
+var reLimit = regexp.MustCompile(`(?is)\b(?:LIMIT\s+(?:\d+|\$\d+)|FETCH\s+(?:FIRST|NEXT)\s+(?:\d+|\$\d+)\s+ROWS?\s+(?:ONLY|WITH\s+TIES))` +
+	`(?:\s+OFFSET\s+(?:\d+|\$\d+)(?:\s+ROWS?)?)?(?:\s+FOR\s+(?:NO\s+KEY\s+)?(?:UPDATE|SHARE|KEY\s+SHARE)\b[^;]*)?\s*;?\s*$`)
+
+// queryMatches checks whether the actual sql and args match the expectation
+// including the rewritten SQL if a pgx.QueryRewriter argument is used
+func (e *queryBasedExpectation) queryMatches(sql string, args []interface{}) error {
+	e.captures = nil
+	err := e.match(sql, args)
+	if err != nil {
+		e.captures = nil
+	}
+	return err
+}
+
+func (e *queryBasedExpectation) match(sql string, args []interface{}) error {
+	matcher := e.matcher()
+	if err := matcher.Match(e.expectSQL, sql); err != nil {
+		return err
+	}
+	for _, re := range e.forbidden {
+		if re.MatchString(sql) {
+			return fmt.Errorf(`actual sql: "%s" matches forbidden pattern "%s"`, sql, re)
+		}
+	}
+	if e.mustHaveLimit && !reLimit.MatchString(sql) {
+		return fmt.Errorf(`actual sql: "%s" does not end with LIMIT clause`, sql)
+	}
+	if _, ok := matcher.(placeholderNormalizedMatcher); ok {
+		args = reorderArgs(e.expectSQL, sql, args)
+	}
+	rewrittenSQL, rewrittenArgs, err := e.argsMatches(sql, args)
+	if err != nil {
+		return err
+	}
+	if e.expectRewrittenArgs != nil {
+		if err := e.argsEqual(e.expectRewrittenArgs, rewrittenArgs); err != nil {
+			return fmt.Errorf("rewritten arguments do not match: %w", err)
+		}
+	}
+	if e.expectArgsInOrder != nil {
+		if rewrittenSQL == "" {
+			return fmt.Errorf("rewritten arguments expected, but no pgx.QueryRewriter argument is passed: '%s'", sql)
+		}
+		if err := e.argsEqual(e.expectArgsInOrder, argsInOrder(rewrittenSQL, rewrittenArgs)); err != nil {
+			return fmt.Errorf("rewritten arguments in placeholders order do not match: %w", err)
+		}
+	}
+	if e.checkPlaceholders {
+		if rewrittenSQL != "" {
+			sql = rewrittenSQL
+		}
+		if err := e.placeholdersMatch(sql, rewrittenArgs); err != nil {
+			return err
+		}
+	}
+	if rewrittenSQL != "" && e.expectRewrittenSQL != "" {
+		return matcher.Match(e.expectRewrittenSQL, rewrittenSQL)
+	}
+	return nil
 }
 
-func (e *queryBasedExpectation) argsMatches(sql string, args []interface{}) (rewrittenSQL string, err error) {
+// placeholdersMatch checks the number of placeholders and its binding to args,
+// query options passed as arguments, e.g. pgx.QueryExecMode, are not bound
+func (e *queryBasedExpectation) placeholdersMatch(sql string, args []interface{}) error {
+	if n := len(placeholders(sql)); n != e.placeholders {
+		return fmt.Errorf("expected %d placeholders, but sql has %d: '%s'", e.placeholders, n, sql)
+	}
+	return placeholdersMatch(sql, stripQueryOptions(args))
+}
+
+// argsString returns string representation of expected arguments
+func (e *queryBasedExpectation) argsString() string {
+	var msg string
+	if len(e.args) == 0 {
+		msg += "\t- is without arguments\n"
+	} else {
+		if e.optionalArgs {
+			msg += "\t- is without arguments or with arguments:\n"
+		} else {
+			msg += "\t- is with arguments:\n"
+		}
+		for i, arg := range e.args {
+			msg += fmt.Sprintf("\t\t%d - %+v\n", i, arg)
+		}
+	}
+	if e.expectRewrittenSQL != "" {
+		msg += fmt.Sprintf("\t- is with rewritten sql: '%s'\n", e.expectRewrittenSQL)
+	}
+	if e.expectRewrittenArgs != nil {
+		msg += "\t- is with rewritten arguments:\n"
+		for i, arg := range e.expectRewrittenArgs {
+			msg += fmt.Sprintf("\t\t%d - %+v\n", i, arg)
+		}
+	}
+	if e.expectArgsInOrder != nil {
+		msg += "\t- is with rewritten arguments in placeholders order:\n"
+		for i, arg := range e.expectArgsInOrder {
+			msg += fmt.Sprintf("\t\t%d - %+v\n", i, arg)
+		}
+	}
+	if e.operation != "" {
+		msg += fmt.Sprintf("\t- is tagged with operation: '%s'\n", e.operation)
+	}
+	if e.contextMatcher != nil {
+		msg += "\t- is with the call context matching\n"
+	}
+	if e.role != "" {
+		msg += fmt.Sprintf("\t- is routed to role: '%s'\n", e.role)
+	}
+	if e.checkPlaceholders {
+		msg += fmt.Sprintf("\t- is with %d placeholders bound to arguments\n", e.placeholders)
+	}
+	if e.mustUsePrepared {
+		msg += "\t- is called with a prepared statement name\n"
+	}
+	for _, re := range e.forbidden {
+		msg += fmt.Sprintf("\t- must not match sql: '%s'\n", re)
+	}
+	if e.mustHaveLimit {
+		msg += "\t- must end with LIMIT clause\n"
+	}
+	return msg
+}
+
+// matcher returns QueryMatcher used by this expectation
+func (e *queryBasedExpectation) matcher() QueryMatcher {
+	if e.queryMatcher == nil {
+		return QueryMatcherRegexp
+	}
+	return e.queryMatcher
+}
+
+func (e *queryBasedExpectation) argsMatches(sql string, args []interface{}) (rewrittenSQL string, rewrittenArgs []interface{}, err error) {
 	eargs := e.args
 	// check for any QueryRewriter arguments: only supported as the first argument
 	if len(args) == 1 {
 		if qrw, ok := args[0].(pgx.QueryRewriter); ok {
 			// note: pgx.Conn is not currently used by the query rewriter
 			if rewrittenSQL, args, err = qrw.RewriteQuery(context.Background(), nil, sql, args); err != nil {
-				return rewrittenSQL, fmt.Errorf("error rewriting query: %w", err)
+				return rewrittenSQL, nil, fmt.Errorf("error rewriting query: %w", err)
 			}
 		}
-		// also do rewriting on the expected args if a QueryRewriter is present
-		if len(eargs) == 1 {
-			if qrw, ok := eargs[0].(pgx.QueryRewriter); ok {
-				if _, eargs, err = qrw.RewriteQuery(context.Background(), nil, sql, eargs); err != nil {
-					return "", fmt.Errorf("error rewriting query expectation: %w", err)
+	}
+	// also do rewriting on the expected args if a QueryRewriter is present,
+	// so the expectation may be compared with the rewritten positional arguments.
+	// If the call uses positional arguments, e.g. $1, the expected SQL is used
+	// for rewriting, so named arguments may be expected for positional calls
+	if len(eargs) == 1 {
+		if qrw, ok := eargs[0].(pgx.QueryRewriter); ok {
+			esql := sql
+			if rewrittenSQL == "" {
+				if esql, err = e.literalSQL(); err != nil {
+					return "", nil, err
 				}
 			}
+			if _, eargs, err = qrw.RewriteQuery(context.Background(), nil, esql, eargs); err != nil {
+				return "", nil, fmt.Errorf("error rewriting query expectation: %w", err)
+			}
+		}
+	}
+	if eargs == nil && (e.expectRewrittenArgs != nil || e.expectArgsInOrder != nil ||
+		rewrittenSQL != "" && e.expectRewrittenSQL != "" || e.checkPlaceholders) {
+		// only rewritten SQL, arguments or placeholders binding are checked
+		return rewrittenSQL, args, nil
+	}
+	if e.optionalArgs && len(args) == 0 {
+		return rewrittenSQL, args, nil
+	}
+	return rewrittenSQL, args, e.argsEqual(eargs, args)
+}
+
+// literalSQL returns the expected SQL to bind expected named arguments of
+// a call with positional arguments. A regular expression is not an SQL,
+// so only patterns without metacharacters may be used
+func (e *queryBasedExpectation) literalSQL() (string, error) {
+	if _, ok := e.matcher().(regexpMatcher); ok && regexp.QuoteMeta(e.expectSQL) != e.expectSQL {
+		return "", fmt.Errorf("named arguments can't be bound to positional ones by sql pattern '%s', "+
+			"use QueryMatcherEqual to expect named arguments for positional calls", e.expectSQL)
+	}
+	return e.expectSQL, nil
+}
+
+// argsEqual compares arguments the same as argsEqual and keeps values
+// for capturing arguments, see capture
+func (e *queryBasedExpectation) argsEqual(eargs, args []interface{}) error {
+	if err := argsEqual(eargs, args); err != nil {
+		return err
+	}
+	for k, earg := range eargs {
+		if c, ok := earg.(argumentCapturer); ok {
+			v := args[k]
+			e.captures = append(e.captures, func() { c.capture(v) })
+		}
+	}
+	return nil
+}
+
+// capture stores arguments of the last matched call into CaptureArg destinations
+func (e *queryBasedExpectation) capture() {
+	for _, fn := range e.captures {
+		fn()
+	}
+	e.captures = nil
+}
+
+// argsInOrder returns arguments bound to placeholders of the SQL in the order
+// placeholders first appear, placeholders without arguments are omitted
+func argsInOrder(sql string, args []interface{}) (ordered []interface{}) {
+	for _, n := range placeholderOrder(sql) {
+		if n > 0 && n <= len(args) {
+			ordered = append(ordered, args[n-1])
 		}
 	}
+	return ordered
+}
+
+// argsEqual compares expected and actual arguments using
+// pgxmock.Argument matchers if any or reflect.DeepEqual otherwise
+func argsEqual(eargs, args []interface{}) error {
 	if len(args) != len(eargs) {
-		return rewrittenSQL, fmt.Errorf("expected %d, but got %d arguments", len(eargs), len(args))
+		return fmt.Errorf("expected %d, but got %d arguments", len(eargs), len(args))
 	}
 	for k, v := range args {
 		// custom argument matcher
 		if matcher, ok := eargs[k].(Argument); ok {
 			if !matcher.Match(v) {
-				return rewrittenSQL, fmt.Errorf("matcher %T could not match %d argument %T - %+v", matcher, k, args[k], args[k])
+				if d, ok := matcher.(argumentDescriber); ok {
+					return fmt.Errorf("matcher %T could not match %d argument: %s", matcher, k, d.describe(v))
+				}
+				return fmt.Errorf("matcher %T could not match %d argument %T - %+v", matcher, k, args[k], args[k])
 			}
 			continue
 		}
 		if darg := eargs[k]; !reflect.DeepEqual(darg, v) {
-			return rewrittenSQL, fmt.Errorf("argument %d expected [%T - %+v] does not match actual [%T - %+v]", k, darg, darg, v, v)
+			return fmt.Errorf("argument %d expected [%T - %+v] does not match actual [%T - %+v]", k, darg, darg, v, v)
 		}
 	}
-	return
+	return nil
 }
 
 // ExpectedClose is used to manage pgx.Close expectation
 // returned by pgxmock.ExpectClose
 type ExpectedClose struct {
 	commonExpectation
+	once     bool // set by ExpectPoolClose
+	repeated uint // Close() calls of the pool after it was closed
 }
 
 // String returns string representation
 func (e *ExpectedClose) String() string {
+	if e.once {
+		return "ExpectedClose => expecting call to Close() of the pool exactly once\n" + e.commonExpectation.String()
+	}
 	return "ExpectedClose => expecting call to Close()\n" + e.commonExpectation.String()
 }
 
@@ -216,35 +598,121 @@ func (e *ExpectedCommit) String() string {
 type ExpectedExec struct {
 	commonExpectation
 	queryBasedExpectation
-	result pgconn.CommandTag
+	result   pgconn.CommandTag
+	results  []pgconn.CommandTag // results of every statement for multi-statement SQL
+	executed int                 // number of statements run by matched calls
 }
 
 // WithArgs will match given expected args to actual database exec operation arguments.
 // if at least one argument does not match, it will return an error. For specific
 // arguments an pgxmock.Argument interface can be used to match an argument.
+// Named arguments, e.g. pgx.NamedArgs, may be expected for calls with
+// positional arguments and vice versa. In the former case the expected SQL
+// binds names to positions, so it must not be a regular expression pattern.
 func (e *ExpectedExec) WithArgs(args ...interface{}) *ExpectedExec {
-	e.args = args
+	e.args, e.optionalArgs = args, false
+	return e
+}
+
+// WithOptionalArgs will match calls without arguments, while arguments of
+// other calls must match the given expected args the same as for WithArgs.
+// Useful for queries taking e.g. an optional filter argument.
+func (e *ExpectedExec) WithOptionalArgs(args ...interface{}) *ExpectedExec {
+	e.args, e.optionalArgs = args, true
 	return e
 }
 
 // WithRewrittenSQL will match given expected expression to a rewritten SQL statement by
-// an pgx.QueryRewriter argument
+// an pgx.QueryRewriter argument. If WithArgs is not used, arguments are not checked.
 func (e *ExpectedExec) WithRewrittenSQL(sql string) *ExpectedExec {
 	e.expectRewrittenSQL = sql
 	return e
 }
 
+// WithRewrittenArgs will match given expected args to the positional arguments
+// produced by an pgx.QueryRewriter argument, e.g. pgx.NamedArgs. If WithArgs
+// is not used, only rewritten arguments are checked.
+func (e *ExpectedExec) WithRewrittenArgs(args ...interface{}) *ExpectedExec {
+	e.expectRewrittenArgs = append([]interface{}{}, args...)
+	return e
+}
+
+// WithRewrittenArgsInOrder will match given expected args to the arguments
+// produced by an pgx.QueryRewriter argument taken in the order placeholders
+// first appear in the rewritten SQL, e.g. to check the named to positional
+// mapping of repeated pgx.NamedArgs. If WithArgs is not used, only rewritten
+// arguments are checked.
+func (e *ExpectedExec) WithRewrittenArgsInOrder(args ...interface{}) *ExpectedExec {
+	e.expectArgsInOrder = append([]interface{}{}, args...)
+	return e
+}
+
+// WithPlaceholderCount will match only SQL having exactly n positional
+// placeholders, e.g. $1 and $2, numbered without gaps and bound to n arguments.
+// Useful to check SQL produced by query builders without hardcoding it.
+// If WithArgs is not used, only the number of arguments is checked.
+func (e *ExpectedExec) WithPlaceholderCount(n int) *ExpectedExec {
+	e.checkPlaceholders, e.placeholders = true, n
+	return e
+}
+
+// WithOperation will match only calls whose context is tagged with
+// the operation name, see ContextWithOperation and OperationContextKey
+func (e *ExpectedExec) WithOperation(operation string) *ExpectedExec {
+	e.operation = operation
+	return e
+}
+
+// WithContextMatcher will match only calls with the context the function
+// returns true for. The function receives the actual context passed to the
+// call, e.g. to check an OpenTelemetry span propagates to the query:
+//
+//	WithContextMatcher(func(ctx context.Context) bool {
+//		return trace.SpanFromContext(ctx).SpanContext().IsValid()
+//	})
+func (e *ExpectedExec) WithContextMatcher(fn func(ctx context.Context) bool) *ExpectedExec {
+	e.contextMatcher = fn
+	return e
+}
+
+// MustUsePreparedStatement will match only calls passing the name of
+// a statement prepared before instead of inline SQL
+func (e *ExpectedExec) MustUsePreparedStatement() *ExpectedExec {
+	e.mustUsePrepared = true
+	return e
+}
+
+// MustNotMatch will match only SQL not matching the regular expression,
+// e.g. `(?i)SELECT \*`, in addition to the expected SQL. May be used
+// several times. Panics if the pattern can't be compiled.
+func (e *ExpectedExec) MustNotMatch(pattern string) *ExpectedExec {
+	e.forbidden = append(e.forbidden, regexp.MustCompile(pattern))
+	return e
+}
+
+// WithQueryMatcher overrides the QueryMatcher set for the mock
+// to match SQL of this expectation only
+func (e *ExpectedExec) WithQueryMatcher(queryMatcher QueryMatcher) *ExpectedExec {
+	e.queryMatcher = queryMatcher
+	return e
+}
+
+// fulfill counts the call and stores captured arguments of the matched call
+func (e *ExpectedExec) fulfill() {
+	e.commonExpectation.fulfill()
+	e.capture()
+}
+
 // String returns string representation
 func (e *ExpectedExec) String() string {
 	msg := "ExpectedExec => expecting call to Exec():\n"
-	msg += fmt.Sprintf("\t- matches sql: '%s'\n", e.expectSQL)
+	msg += fmt.Sprintf("\t- matches sql (%s): '%s'\n", matcherName(e.matcher()), e.expectSQL)
 
-	if len(e.args) == 0 {
-		msg += "\t- is without arguments\n"
-	} else {
-		msg += "\t- is with arguments:\n"
-		for i, arg := range e.args {
-			msg += fmt.Sprintf("\t\t%d - %+v\n", i, arg)
+	msg += e.argsString()
+	if len(e.results) > 1 {
+		msg += fmt.Sprintf("\t- runs %d statements with results:\n", len(e.results))
+		for i, res := range e.results {
+			msg += fmt.Sprintf("\t\t%d - %s\n", i, res)
 		}
 	}
 	if e.result.String() != "" {
@@ -259,14 +727,66 @@ func (e *ExpectedExec) String() string {
 // to build a corresponding result.
 func (e *ExpectedExec) WillReturnResult(result pgconn.CommandTag) *ExpectedExec {
 	e.result = result
+	e.results = nil
 	return e
 }
 
+// WillReturnZeroAffected arranges for an expected Exec() to return the command
+// tag of op with no rows affected, the same as PostgreSQL does, e.g. "INSERT 0 0"
+// for the INSERT ... ON CONFLICT DO NOTHING statement skipping the conflicting row
+func (e *ExpectedExec) WillReturnZeroAffected(op string) *ExpectedExec {
+	tag := op + " 0"
+	if strings.EqualFold(op, "INSERT") {
+		tag = op + " 0 0" // the OID is always 0
+	}
+	return e.WillReturnResult(pgconn.NewCommandTag(tag))
+}
+
+// WillReturnResultAndError arranges for an expected Exec() to return both
+// the result and the error, e.g. for a statement that was partially applied
+// before failing. Code inspecting rows affected on error may be tested this way.
+func (e *ExpectedExec) WillReturnResultAndError(result pgconn.CommandTag, err error) *ExpectedExec {
+	e.WillReturnResult(result)
+	e.err = err
+	return e
+}
+
+// WillReturnResults arranges for an expected Exec() of multi-statement SQL
+// to return results for every statement. The same as pgx does, Exec() returns
+// the result of the last statement only. Exec() of SQL with more or fewer
+// statements than results doesn't match the expectation.
+func (e *ExpectedExec) WillReturnResults(results ...pgconn.CommandTag) *ExpectedExec {
+	e.results = results
+	e.result = pgconn.CommandTag{}
+	if len(results) > 0 {
+		e.result = results[len(results)-1]
+	}
+	return e
+}
+
+// StatementsExecuted returns the number of statements run by
+// all matched Exec() calls of this expectation
+func (e *ExpectedExec) StatementsExecuted() int {
+	e.Lock()
+	defer e.Unlock()
+	return e.executed
+}
+
+// statementsMatch checks that SQL runs as many statements as results expected
+func (e *ExpectedExec) statementsMatch(sql string) error {
+	if len(e.results) == 0 {
+		return nil
+	}
+	if n := len(splitStatements(sql)); n != len(e.results) {
+		return fmt.Errorf(`actual sql: "%s" runs %d statements, but %d results expected`, sql, n, len(e.results))
+	}
+	return nil
+}
+
 // ExpectedPrepare is used to manage pgx.Prepare or pgx.Tx.Prepare expectations.
 // Returned by pgxmock.ExpectPrepare.
 type ExpectedPrepare struct {
 	commonExpectation
-	mock           *pgxmock
 	expectStmtName string
 	expectSQL      string
 	deallocateErr  error
@@ -298,7 +818,8 @@ func (e *ExpectedPrepare) WillBeDeallocated() *ExpectedPrepare {
 func (e *ExpectedPrepare) ExpectQuery() *ExpectedQuery {
 	eq := &ExpectedQuery{}
 	eq.expectSQL = e.expectStmtName
-	e.mock.expectations = append(e.mock.expectations, eq)
+	eq.queryMatcher = e.mock.queryMatcher
+	e.mock.expect(eq)
 	return eq
 }
 
@@ -307,17 +828,23 @@ func (e *ExpectedPrepare) ExpectQuery() *ExpectedQuery {
 func (e *ExpectedPrepare) ExpectExec() *ExpectedExec {
 	eq := &ExpectedExec{}
 	eq.expectSQL = e.expectStmtName
-	e.mock.expectations = append(e.mock.expectations, eq)
+	eq.queryMatcher = e.mock.queryMatcher
+	e.mock.expect(eq)
 	return eq
 }
 
 // String returns string representation
 func (e *ExpectedPrepare) String() string {
-	msg := "ExpectedPrepare => expecting call to Prepare():"
-	msg += fmt.Sprintf("\t- matches statement name: '%s'", e.expectStmtName)
-	msg += fmt.Sprintf("\t- matches sql: '%s'\n", e.expectSQL)
+	msg := fmt.Sprintf("ExpectedPrepare => expecting call to Prepare() of statement '%s':\n", e.expectStmtName)
+	msg += fmt.Sprintf("\t- matches sql (%s): '%s'\n", matcherName(e.mock.queryMatcher), e.expectSQL)
 	if e.deallocateErr != nil {
-		msg += fmt.Sprintf("\t- returns error on Close: %s", e.deallocateErr)
+		msg += fmt.Sprintf("\t- returns error on Close: %s\n", e.deallocateErr)
+	}
+	if e.mustBeClosed {
+		msg += "\t- expects to be deallocated\n"
+	}
+	if e.deallocated {
+		msg += "\t- was deallocated\n"
 	}
 	return msg + e.commonExpectation.String()
 }
@@ -333,63 +860,297 @@ func (e *ExpectedPing) String() string {
 	return msg + e.commonExpectation.String()
 }
 
+// ExpectedCancelRequest is used to manage CancelRequest() expectations.
+// Returned by *Pgxmock.ExpectCancelRequest.
+type ExpectedCancelRequest struct {
+	commonExpectation
+}
+
+// String returns string representation
+func (e *ExpectedCancelRequest) String() string {
+	msg := "ExpectedCancelRequest => expecting call to CancelRequest()\n"
+	return msg + e.commonExpectation.String()
+}
+
+// ExpectedAcquire is used to manage pgxpool.Pool.Acquire() expectations.
+// Returned by *Pgxmock.ExpectAcquire.
+type ExpectedAcquire struct {
+	commonExpectation
+}
+
+// WillTimeoutAcquire allows to simulate the pool unable to hand out a connection,
+// Acquire() returns context.DeadlineExceeded after the given duration
+func (e *ExpectedAcquire) WillTimeoutAcquire(after time.Duration) CallModifier {
+	e.plannedDelay = after
+	e.err = context.DeadlineExceeded
+	return e
+}
+
+// String returns string representation
+func (e *ExpectedAcquire) String() string {
+	msg := "ExpectedAcquire => expecting call to Acquire()\n"
+	return msg + e.commonExpectation.String()
+}
+
 // ExpectedQuery is used to manage *pgx.Conn.Query, *pgx.Conn.QueryRow, *pgx.Tx.Query,
 // *pgx.Tx.QueryRow, *pgx.Stmt.Query or *pgx.Stmt.QueryRow expectations
 type ExpectedQuery struct {
 	commonExpectation
 	queryBasedExpectation
 	rows             pgx.Rows
+	row              pgx.Row // custom row returned by QueryRow()
+	rowsFn           func(args []interface{}) *Rows
+	groupsFn         func(groups []string) *Rows
 	rowsMustBeClosed bool
 	rowsWereClosed   bool
+	rowDelay         time.Duration // delay before every row is returned
+	singleRow        bool          // QueryRow() fails for more than one row
 }
 
 // WithArgs will match given expected args to actual database query arguments.
 // if at least one argument does not match, it will return an error. For specific
 // arguments an pgxmock.Argument interface can be used to match an argument.
+// Named arguments, e.g. pgx.NamedArgs, may be expected for calls with
+// positional arguments and vice versa. In the former case the expected SQL
+// binds names to positions, so it must not be a regular expression pattern.
 func (e *ExpectedQuery) WithArgs(args ...interface{}) *ExpectedQuery {
-	e.args = args
+	e.args, e.optionalArgs = args, false
+	return e
+}
+
+// WithOptionalArgs will match calls without arguments, while arguments of
+// other calls must match the given expected args the same as for WithArgs.
+// Useful for queries taking e.g. an optional filter argument.
+func (e *ExpectedQuery) WithOptionalArgs(args ...interface{}) *ExpectedQuery {
+	e.args, e.optionalArgs = args, true
 	return e
 }
 
 // WithRewrittenSQL will match given expected expression to a rewritten SQL statement by
-// an pgx.QueryRewriter argument
+// an pgx.QueryRewriter argument. If WithArgs is not used, arguments are not checked.
 func (e *ExpectedQuery) WithRewrittenSQL(sql string) *ExpectedQuery {
 	e.expectRewrittenSQL = sql
 	return e
 }
 
+// WithRewrittenArgs will match given expected args to the positional arguments
+// produced by an pgx.QueryRewriter argument, e.g. pgx.NamedArgs. If WithArgs
+// is not used, only rewritten arguments are checked.
+func (e *ExpectedQuery) WithRewrittenArgs(args ...interface{}) *ExpectedQuery {
+	e.expectRewrittenArgs = append([]interface{}{}, args...)
+	return e
+}
+
+// WithRewrittenArgsInOrder will match given expected args to the arguments
+// produced by an pgx.QueryRewriter argument taken in the order placeholders
+// first appear in the rewritten SQL, e.g. to check the named to positional
+// mapping of repeated pgx.NamedArgs. If WithArgs is not used, only rewritten
+// arguments are checked.
+func (e *ExpectedQuery) WithRewrittenArgsInOrder(args ...interface{}) *ExpectedQuery {
+	e.expectArgsInOrder = append([]interface{}{}, args...)
+	return e
+}
+
+// WithQueryMatcher overrides the QueryMatcher set for the mock
+// to match SQL of this expectation only
+func (e *ExpectedQuery) WithQueryMatcher(queryMatcher QueryMatcher) *ExpectedQuery {
+	e.queryMatcher = queryMatcher
+	return e
+}
+
+// WithPlaceholderCount will match only SQL having exactly n positional
+// placeholders, e.g. $1 and $2, numbered without gaps and bound to n arguments.
+// Useful to check SQL produced by query builders without hardcoding it.
+// If WithArgs is not used, only the number of arguments is checked.
+func (e *ExpectedQuery) WithPlaceholderCount(n int) *ExpectedQuery {
+	e.checkPlaceholders, e.placeholders = true, n
+	return e
+}
+
+// WithOperation will match only calls whose context is tagged with
+// the operation name, see ContextWithOperation and OperationContextKey
+func (e *ExpectedQuery) WithOperation(operation string) *ExpectedQuery {
+	e.operation = operation
+	return e
+}
+
+// WithContextMatcher will match only calls with the context the function
+// returns true for. The function receives the actual context passed to the
+// call, e.g. to check an OpenTelemetry span propagates to the query:
+//
+//	WithContextMatcher(func(ctx context.Context) bool {
+//		return trace.SpanFromContext(ctx).SpanContext().IsValid()
+//	})
+func (e *ExpectedQuery) WithContextMatcher(fn func(ctx context.Context) bool) *ExpectedQuery {
+	e.contextMatcher = fn
+	return e
+}
+
+// MustUsePreparedStatement will match only calls passing the name of
+// a statement prepared before instead of inline SQL
+func (e *ExpectedQuery) MustUsePreparedStatement() *ExpectedQuery {
+	e.mustUsePrepared = true
+	return e
+}
+
+// MustNotMatch will match only SQL not matching the regular expression,
+// e.g. `(?i)SELECT \*`, in addition to the expected SQL. May be used
+// several times. Panics if the pattern can't be compiled.
+func (e *ExpectedQuery) MustNotMatch(pattern string) *ExpectedQuery {
+	e.forbidden = append(e.forbidden, regexp.MustCompile(pattern))
+	return e
+}
+
+// MustHaveLimit will match only SQL ending with LIMIT n or FETCH FIRST n
+// ROWS ONLY clause, optionally followed by OFFSET and FOR UPDATE clauses,
+// e.g. to catch unbounded list queries. LIMIT ALL is not accepted.
+func (e *ExpectedQuery) MustHaveLimit() *ExpectedQuery {
+	e.mustHaveLimit = true
+	return e
+}
+
+// WillDelayPerRow allows to specify duration for which every row of the
+// result will be delayed by rows.Next(), while WillDelayFor delays only
+// the initial response. May be used together with Context.
+func (e *ExpectedQuery) WillDelayPerRow(duration time.Duration) *ExpectedQuery {
+	e.rowDelay = duration
+	return e
+}
+
 // RowsWillBeClosed expects this query rows to be closed.
 func (e *ExpectedQuery) RowsWillBeClosed() *ExpectedQuery {
 	e.rowsMustBeClosed = true
 	return e
 }
 
+// ExpectSingleRow makes QueryRow() fail if rows returned contain more than
+// one row, pgx would silently take the first one. Useful to assert uniqueness
+// invariants of queries. Query() calls are not affected.
+func (e *ExpectedQuery) ExpectSingleRow() *ExpectedQuery {
+	e.singleRow = true
+	return e
+}
+
+// fulfill counts the call and stores captured arguments of the matched call
+func (e *ExpectedQuery) fulfill() {
+	e.commonExpectation.fulfill()
+	e.capture()
+}
+
+// fulfilled reports whether all fetches of the cursor rows were called
+func (e *ExpectedQuery) fulfilled() bool {
+	if n := e.cursorFetches(); n > 0 && !e.retryable && e.plannedCalls == 0 {
+		return e.skipped || e.triggered >= uint(n)
+	}
+	return e.commonExpectation.fulfilled()
+}
+
 // String returns string representation
 func (e *ExpectedQuery) String() string {
 	msg := "ExpectedQuery => expecting call to Query() or to QueryRow():\n"
-	msg += fmt.Sprintf("\t- matches sql: '%s'\n", e.expectSQL)
+	msg += fmt.Sprintf("\t- matches sql (%s): '%s'\n", matcherName(e.matcher()), e.expectSQL)
 
-	if len(e.args) == 0 {
-		msg += "\t- is without arguments\n"
-	} else {
-		msg += "\t- is with arguments:\n"
-		for i, arg := range e.args {
-			msg += fmt.Sprintf("\t\t%d - %+v\n", i, arg)
-		}
-	}
+	msg += e.argsString()
 	if e.rows != nil {
 		msg += fmt.Sprintf("%s\n", e.rows)
 	}
+	if e.row != nil {
+		msg += fmt.Sprintf("\t- returns custom row: %T\n", e.row)
+	}
+	if e.rowsFn != nil {
+		msg += "\t- returns rows built from arguments\n"
+	}
+	if e.groupsFn != nil {
+		msg += "\t- returns rows built from sql capture groups\n"
+	}
+	if e.rowDelay > 0 {
+		msg += fmt.Sprintf("\t- delayed every row for: %v\n", e.rowDelay)
+	}
+	if e.singleRow {
+		msg += "\t- expects a single row for QueryRow()\n"
+	}
+	if n := e.cursorFetches(); n > 0 {
+		msg += fmt.Sprintf("\t- returns rows as a cursor in %d fetches\n", n)
+	}
 	return msg + e.commonExpectation.String()
 }
 
 // WillReturnRows specifies the set of resulting rows that will be returned
-// by the triggered query
+// by the triggered query. Without rows a valid empty result is returned with
+// no columns, the same as pgx does for commands like SET.
 func (e *ExpectedQuery) WillReturnRows(rows ...*Rows) *ExpectedQuery {
+	if len(rows) == 0 {
+		rows = []*Rows{NewRows(nil)}
+	}
 	e.rows = &rowSets{sets: rows, ex: e}
 	return e
 }
 
+// cursorFetches returns the number of fetches awaited by rows returned
+// as a cursor, see Rows.AsCursor, zero if rows are not a cursor
+func (e *ExpectedQuery) cursorFetches() int {
+	if rs, ok := e.rows.(*rowSets); ok && len(rs.sets) == 1 && rs.sets[0].fetchSize > 0 {
+		return rs.sets[0].fetches()
+	}
+	return 0
+}
+
+// WillReturnRow specifies the custom pgx.Row that will be returned as is
+// by the triggered QueryRow(). Useful to simulate Scan() behavior, e.g. a
+// decode panic, that can't be expressed with Rows. Such expectation is
+// not matched by Query().
+func (e *ExpectedQuery) WillReturnRow(row pgx.Row) *ExpectedQuery {
+	e.row = row
+	return e
+}
+
+// WillReturnRowsFromMatch specifies the function building resulting rows
+// from the groups captured by the expected regexp in the actual SQL of every
+// triggered query, e.g. the table name of "SELECT * FROM (\\w+)". The first
+// capture group is groups[0], groups are nil for other query matchers.
+func (e *ExpectedQuery) WillReturnRowsFromMatch(fn func(groups []string) *Rows) *ExpectedQuery {
+	e.groupsFn = fn
+	return e
+}
+
+// captureGroups returns groups captured by the expected regexp in the SQL
+func (e *queryBasedExpectation) captureGroups(sql string) ([]string, bool) {
+	if e.matcher() != QueryMatcherRegexp {
+		return nil, false
+	}
+	re, err := regexp.Compile(stripQuery(e.expectSQL))
+	if err != nil {
+		return nil, false
+	}
+	m := re.FindStringSubmatch(stripQuery(sql))
+	if m == nil {
+		return nil, false
+	}
+	return m[1:], true
+}
+
+// WillReturnRowsFromArgs specifies the function building resulting rows
+// from the actual arguments of every triggered query, e.g. to return
+// inserted values by INSERT ... RETURNING without hardcoding them
+func (e *ExpectedQuery) WillReturnRowsFromArgs(fn func(args []interface{}) *Rows) *ExpectedQuery {
+	e.rowsFn = fn
+	return e
+}
+
+// WillReturnRowsCSV is a shortcut to build resulting rows from columns and
+// the csv string, see Rows.FromCSVString(). Values are parsed with CSVColumnParser,
+// which returns strings and NULL as nil, unless it is overridden.
+func (e *ExpectedQuery) WillReturnRowsCSV(columns []string, csv string) *ExpectedQuery {
+	return e.WillReturnRows(NewRows(columns).FromCSVString(csv))
+}
+
+// WillReturnRowsCSVWithColumnDefinition is the same as WillReturnRowsCSV, but
+// values are parsed according to DataTypeOID of the columns, e.g. int4 values
+// become int32 and timestamptz ones time.Time, see Rows.FromCSVString()
+func (e *ExpectedQuery) WillReturnRowsCSVWithColumnDefinition(columns []pgconn.FieldDescription, csv string) *ExpectedQuery {
+	return e.WillReturnRows(NewRowsWithColumnDefinition(columns...).FromCSVString(csv))
+}
+
 // ExpectedCopyFrom is used to manage *pgx.Conn.CopyFrom expectations.
 // Returned by *Pgxmock.ExpectCopyFrom.
 type ExpectedCopyFrom struct {
@@ -397,34 +1158,67 @@ type ExpectedCopyFrom struct {
 	expectedTableName pgx.Identifier
 	expectedColumns   []string
 	rowsAffected      int64
+	failAfterRows     int64 // number of source rows read before failErr is returned
+	failErr           error
 }
 
 // String returns string representation
 func (e *ExpectedCopyFrom) String() string {
-	msg := "ExpectedCopyFrom => expecting CopyFrom which:"
-	msg += "\n  - matches table name: '" + e.expectedTableName.Sanitize() + "'"
-	msg += fmt.Sprintf("\n  - matches column names: '%+v'", e.expectedColumns)
-
-	if e.err != nil {
-		msg += fmt.Sprintf("\n  - should returns error: %s", e.err)
+	msg := "ExpectedCopyFrom => expecting call to CopyFrom():\n"
+	msg += fmt.Sprintf("\t- matches table name: '%s'\n", e.expectedTableName.Sanitize())
+	msg += fmt.Sprintf("\t- matches column names: '%+v'\n", e.expectedColumns)
+	msg += fmt.Sprintf("\t- returns result: %s\n", e.CommandTag())
+	if e.failErr != nil {
+		msg += fmt.Sprintf("\t- fails after %d rows with error: %s\n", e.failAfterRows, e.failErr)
 	}
-
-	return msg
+	return msg + e.commonExpectation.String()
 }
 
-// WillReturnResult arranges for an expected CopyFrom() to return a number of rows affected
+// WillReturnResult arranges for an expected CopyFrom() to return a number of rows affected.
+// The same way as pgx.Conn.CopyFrom() does, the number of rows is taken from
+// the "COPY n" command tag, see CommandTag()
 func (e *ExpectedCopyFrom) WillReturnResult(result int64) *ExpectedCopyFrom {
 	e.rowsAffected = result
 	return e
 }
 
+// WillFailAfterRows arranges for an expected CopyFrom() to read k rows from
+// the pgx.CopyFromSource and then return the err along with the number of
+// rows read, e.g. to simulate a constraint violation in the middle of the copy
+func (e *ExpectedCopyFrom) WillFailAfterRows(k int, err error) *ExpectedCopyFrom {
+	e.failAfterRows = int64(k)
+	e.failErr = err
+	return e
+}
+
+// copyRows reads rows from the source until the planned failure
+func (e *ExpectedCopyFrom) copyRows(rowSrc pgx.CopyFromSource) (int64, error) {
+	var n int64
+	for ; n < e.failAfterRows && rowSrc != nil && rowSrc.Next(); n++ {
+		if _, err := rowSrc.Values(); err != nil {
+			return n, err
+		}
+	}
+	if rowSrc != nil && rowSrc.Err() != nil {
+		return n, rowSrc.Err()
+	}
+	return n, e.failErr
+}
+
+// CommandTag returns the "COPY n" command tag the PostgreSQL server would
+// send for this CopyFrom() call, where n is the number of rows copied
+func (e *ExpectedCopyFrom) CommandTag() pgconn.CommandTag {
+	return NewResult("COPY", e.rowsAffected)
+}
+
 // ExpectedReset is used to manage pgx.Reset expectation
 type ExpectedReset struct {
 	commonExpectation
 }
 
+// String returns string representation
 func (e *ExpectedReset) String() string {
-	return "ExpectedReset => expecting database Reset"
+	return "ExpectedReset => expecting call to Reset()\n" + e.commonExpectation.String()
 }
 
 // ExpectedRollback is used to manage pgx.Tx.Rollback expectation
@@ -435,9 +1229,5 @@ type ExpectedRollback struct {
 
 // String returns string representation
 func (e *ExpectedRollback) String() string {
-	msg := "ExpectedRollback => expecting transaction Rollback"
-	if e.err != nil {
-		msg += fmt.Sprintf(", which should return error: %s", e.err)
-	}
-	return msg
+	return "ExpectedRollback => expecting call to Tx.Rollback()\n" + e.commonExpectation.String()
 }
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -46,6 +47,49 @@ func TestMaybe(t *testing.T) {
 	a.NoError(mock.ExpectationsWereMet())
 }
 
+func TestMaybeInOrder(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+	mock.ExpectExec("DELETE FROM cache").WithArgs("users").WillReturnResult(NewResult("DELETE", 1)).Maybe()
+	mock.ExpectExec("DELETE FROM cache").WithArgs("orders").WillReturnResult(NewResult("DELETE", 2))
+	mock.ExpectPing().Maybe()
+	mock.ExpectPing()
+	mock.ExpectRollback().Maybe()
+
+	_, err := mock.Exec(ctx, "DELETE FROM cache WHERE name = $1", "orders")
+	a.NoError(err, "optional expectation not matching the call must be skipped")
+	_, err = mock.Exec(ctx, "DELETE FROM cache WHERE name = $1", "users")
+	a.Error(err, "skipped optional expectation must not match after the next required one")
+	a.NoError(mock.Ping(ctx), "optional expectation matching the call is fulfilled")
+	a.NoError(mock.Ping(ctx))
+	a.Error(mock.Ping(ctx))
+	a.NoError(mock.ExpectationsWereMet())
+
+	mock.ExpectPing().Maybe()
+	mock.ExpectBegin()
+	mock.ExpectPing()
+	_, err = mock.Begin(ctx)
+	a.NoError(err)
+	a.ErrorContains(mock.ExpectationsWereMet(), "expecting call to Ping()")
+	a.NoError(mock.Ping(ctx), "optional expectation of other method is not skipped for good")
+	a.NoError(mock.Ping(ctx), "required expectations are still matched in order")
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestMaybeOfOtherMethodInOrder(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+	mock.ExpectClose().Maybe()
+	mock.ExpectExec("DELETE FROM cache").WillReturnResult(NewResult("DELETE", 1))
+
+	_, err := mock.Exec(ctx, "DELETE FROM cache")
+	a.NoError(err)
+	a.NoError(mock.Close(ctx), "optional expectation of other method must not be skipped for good")
+	a.NoError(mock.ExpectationsWereMet())
+}
+
 func TestPanic(t *testing.T) {
 	t.Parallel()
 	mock, _ := NewConn()
@@ -93,6 +137,32 @@ func TestCopyFromBug(t *testing.T) {
 	a.NoError(mock.ExpectationsWereMet())
 }
 
+func ExampleExpectedPrepare() {
+	mock, _ := NewConn()
+	ex := mock.ExpectPrepare("get_user", "SELECT name FROM users WHERE id = \\$1").
+		WillReturnCloseError(errors.New("deallocate failed")).
+		WillBeDeallocated()
+	ex.Label("user lookup")
+
+	fmt.Print(ex)
+	_, _ = mock.Prepare(ctx, "get_user", "SELECT name FROM users WHERE id = $1")
+	fmt.Println(mock.Deallocate(ctx, "get_user"))
+	fmt.Print(ex)
+	// Output:
+	// ExpectedPrepare => expecting call to Prepare() of statement 'get_user':
+	// 	- matches sql (regexp): 'SELECT name FROM users WHERE id = \$1'
+	// 	- returns error on Close: deallocate failed
+	// 	- expects to be deallocated
+	// 	- labeled as: 'user lookup'
+	// deallocate failed
+	// ExpectedPrepare => expecting call to Prepare() of statement 'get_user':
+	// 	- matches sql (regexp): 'SELECT name FROM users WHERE id = \$1'
+	// 	- returns error on Close: deallocate failed
+	// 	- expects to be deallocated
+	// 	- was deallocated
+	// 	- labeled as: 'user lookup'
+}
+
 func ExampleExpectedExec() {
 	mock, _ := NewConn()
 	ex := mock.ExpectExec("^INSERT (.+)").WillReturnResult(NewResult("INSERT", 15))
@@ -107,7 +177,7 @@ func ExampleExpectedExec() {
 	fmt.Print(res)
 	// Output:
 	// ExpectedExec => expecting call to Exec():
-	// 	- matches sql: '^INSERT (.+)'
+	// 	- matches sql (regexp): '^INSERT (.+)'
 	// 	- is without arguments
 	// 	- returns result: INSERT 15
 	// 	- delayed execution for: 1s
@@ -115,7 +185,7 @@ func ExampleExpectedExec() {
 	// 	- execution calls awaited: 2
 	// INSERT 15
 	// ExpectedExec => expecting call to Exec():
-	// 	- matches sql: '^INSERT (.+)'
+	// 	- matches sql (regexp): '^INSERT (.+)'
 	// 	- is with arguments:
 	// 		0 - 42
 	// 	- returns result: INSERT 15
@@ -298,6 +368,148 @@ func TestWithRewrittenSQL(t *testing.T) {
 	a.Error(mock.ExpectationsWereMet())
 }
 
+func TestExecWithRewrittenSQL(t *testing.T) {
+	t.Parallel()
+	mock, err := NewConn(QueryMatcherOption(QueryMatcherEqual))
+	a := assert.New(t)
+	a.NoError(err)
+
+	u := user{ID: 42, name: "John", email: pgtype.Text{String: "john@example.com", Valid: true}}
+	ex := mock.ExpectExec(`DELETE`).
+		WithArgs(&u).
+		WithRewrittenSQL(`DELETE FROM users WHERE id = $1`).
+		WithRewrittenArgs(int64(42)).
+		WillReturnResult(NewResult("DELETE", 1))
+	a.Contains(ex.String(), "is with rewritten sql")
+	mock.ExpectExec(`UPDATE`).
+		WithRewrittenSQL(`UPDATE users SET username = $1, email = $2 WHERE id = $1`).
+		WillReturnResult(NewResult("UPDATE", 1))
+
+	res, err := mock.Exec(context.Background(), "DELETE", &u)
+	a.NoError(err)
+	a.EqualValues(1, res.RowsAffected())
+	_, err = mock.Exec(context.Background(), "UPDATE", &u)
+	a.NoError(err, "rewritten SQL must be matched without WithArgs")
+	a.NoError(mock.ExpectationsWereMet())
+
+	mock.ExpectExec(`DELETE`).
+		WithArgs(&u).
+		WithRewrittenSQL(`DELETE FROM users WHERE email = $1`).
+		WillReturnResult(NewResult("DELETE", 1))
+	_, err = mock.Exec(context.Background(), "DELETE", &u)
+	a.Error(err)
+	a.Error(mock.ExpectationsWereMet())
+}
+
+func TestWithRewrittenArgs(t *testing.T) {
+	t.Parallel()
+	mock, err := NewConn(QueryMatcherOption(QueryMatcherEqual))
+	a := assert.New(t)
+	a.NoError(err)
+
+	sql := `INSERT INTO users(username, password) VALUES (@user, @password)`
+	args := pgx.NamedArgs{"user": "John", "password": "strong"}
+
+	ex := mock.ExpectExec(sql).
+		WithRewrittenSQL(`INSERT INTO users(username, password) VALUES ($1, $2)`).
+		WithRewrittenArgs("John", AnyArg()).
+		WillReturnResult(NewResult("INSERT", 1))
+	a.Contains(ex.String(), "is with rewritten arguments")
+	_, err = mock.Exec(ctx, sql, args)
+	a.NoError(err)
+
+	u := user{ID: 42, name: "John"}
+	mock.ExpectExec(`UPDATE`).
+		WithArgs(&u).
+		WithRewrittenArgs(int64(42), "John", pgtype.Text{}).
+		WillReturnResult(NewResult("UPDATE", 1))
+	_, err = mock.Exec(ctx, "UPDATE", &u)
+	a.NoError(err)
+
+	mock.ExpectQuery(sql).
+		WithArgs(args).
+		WithRewrittenArgs("strong", "John").
+		WillReturnRows()
+	_, err = mock.Query(ctx, sql, args)
+	a.ErrorContains(err, "rewritten arguments do not match")
+	a.Error(mock.ExpectationsWereMet())
+}
+
+// reversedRewriter numbers placeholders in the reverse order of appearance
+type reversedRewriter struct{ a, b any }
+
+func (r reversedRewriter) RewriteQuery(_ context.Context, _ *pgx.Conn, sql string, _ []any) (string, []any, error) {
+	return strings.NewReplacer("@a", "$2", "@b", "$1").Replace(sql), []any{r.b, r.a}, nil
+}
+
+func TestWithRewrittenArgsInOrder(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+
+	sql := `SELECT id FROM users WHERE name = @name OR nick = @nick OR login = @name`
+	ex := mock.ExpectQuery("SELECT id FROM users").
+		WithRewrittenArgsInOrder("john", "johnny").
+		WillReturnRows(NewRows([]string{"id"}))
+	a.Contains(ex.String(), "is with rewritten arguments in placeholders order")
+	rows, err := mock.Query(ctx, sql, pgx.NamedArgs{"nick": "johnny", "name": "john"})
+	a.NoError(err, "repeated named argument must be bound once")
+	rows.Close()
+
+	sql = "UPDATE t SET a = @a WHERE b = @b"
+	mock.ExpectExec("UPDATE t").
+		WithRewrittenArgs(2, 1).
+		WithRewrittenArgsInOrder(1, 2).
+		WillReturnResult(NewResult("UPDATE", 1))
+	_, err = mock.Exec(ctx, sql, reversedRewriter{1, 2})
+	a.NoError(err, "arguments must be taken in the order of placeholders appearance")
+
+	mock.ExpectExec("UPDATE t").WithRewrittenArgsInOrder(2, 1).WillReturnResult(NewResult("UPDATE", 1))
+	_, err = mock.Exec(ctx, sql, reversedRewriter{1, 2})
+	a.ErrorContains(err, "rewritten arguments in placeholders order do not match")
+	_, err = mock.Exec(ctx, "UPDATE t SET a = $1 WHERE b = $2", 2, 1)
+	a.ErrorContains(err, "no pgx.QueryRewriter argument is passed")
+	a.Error(mock.ExpectationsWereMet())
+}
+
+func TestStructArgsRewriter(t *testing.T) {
+	t.Parallel()
+	mock, err := NewConn(QueryMatcherOption(QueryMatcherEqual))
+	a := assert.New(t)
+	a.NoError(err)
+
+	u := user{ID: 42, name: "John", email: pgtype.Text{String: "john@example.com", Valid: true}}
+
+	// both expectation and call use the same rewriter
+	mock.ExpectExec(`DELETE`).
+		WithArgs(&u).
+		WillReturnResult(NewResult("DELETE", 1))
+	_, err = mock.Exec(ctx, "DELETE", &u)
+	a.NoError(err)
+
+	// expectation uses the rewriter, call uses rewritten positional arguments
+	mock.ExpectExec(`DELETE`).
+		WithArgs(&u).
+		WillReturnResult(NewResult("DELETE", 1))
+	_, err = mock.Exec(ctx, "DELETE", u.ID)
+	a.NoError(err)
+
+	// expectation uses positional arguments, call uses the rewriter
+	mock.ExpectQuery(`DELETE`).
+		WithArgs(u.ID).
+		WillReturnRows()
+	_, err = mock.Query(ctx, "DELETE", &u)
+	a.NoError(err)
+	a.NoError(mock.ExpectationsWereMet())
+
+	mock.ExpectExec(`DELETE`).
+		WithArgs(&u).
+		WillReturnResult(NewResult("DELETE", 1))
+	_, err = mock.Exec(ctx, "DELETE", &user{ID: 1})
+	a.Error(err, "rewritten arguments should not match")
+	a.Error(mock.ExpectationsWereMet())
+}
+
 func TestQueryRewriter(t *testing.T) {
 	t.Parallel()
 	mock, err := NewConn(QueryMatcherOption(QueryMatcherEqual))
@@ -322,3 +534,790 @@ func TestQueryRewriter(t *testing.T) {
 	a.Error(err)
 	a.NoError(mock.ExpectationsWereMet())
 }
+
+func TestCopyFromCommandTag(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+
+	ex := mock.ExpectCopyFrom(pgx.Identifier{"foo"}, []string{"bar"}).WillReturnResult(42)
+	a.False(ex.CommandTag().Insert())
+	a.Equal("COPY 42", ex.CommandTag().String())
+	a.Contains(ex.String(), "returns result: COPY 42")
+
+	r, err := mock.CopyFrom(ctx, pgx.Identifier{"foo"}, []string{"bar"}, nil)
+	a.NoError(err)
+	a.EqualValues(ex.CommandTag().RowsAffected(), r)
+
+	ex = mock.ExpectCopyFrom(pgx.Identifier{"foo"}, []string{"bar"})
+	ex.WillReturnError(errors.New("copy failed"))
+	a.Equal("COPY 0", ex.CommandTag().String())
+	r, err = mock.CopyFrom(ctx, pgx.Identifier{"foo"}, []string{"bar"}, nil)
+	a.Error(err)
+	a.Zero(r, "pgx returns zero rows copied on error without result")
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestPriority(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+
+	mock.ExpectQuery("SELECT .*").
+		WillReturnRows(NewRows([]string{"fallback"}).AddRow(true))
+	mock.ExpectQuery("SELECT .* FROM users").
+		WillReturnRows(NewRows([]string{"name"}).AddRow("John")).
+		Priority(10)
+
+	var name string
+	a.NoError(mock.QueryRow(ctx, "SELECT name FROM users").Scan(&name))
+	a.Equal("John", name)
+
+	var fallback bool
+	a.NoError(mock.QueryRow(ctx, "SELECT version()").Scan(&fallback))
+	a.True(fallback)
+	a.NoError(mock.ExpectationsWereMet())
+
+	ex := mock.ExpectPing()
+	ex.Priority(-1)
+	a.Contains(ex.String(), "matching priority: -1")
+}
+
+func TestPriorityGenericFirst(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	for _, ordered := range []bool{true, false} {
+		mock, _ := NewConn()
+		mock.MatchExpectationsInOrder(ordered)
+		mock.ExpectQuery("SELECT .*").
+			WillReturnRows(NewRows([]string{"fallback"}).AddRow(true))
+		mock.ExpectQuery("SELECT .* FROM users").
+			WillReturnRows(NewRows([]string{"name"}).AddRow("John")).
+			Priority(10)
+
+		var fallback bool
+		a.NoError(mock.QueryRow(ctx, "SELECT version()").Scan(&fallback),
+			"non-matching expectation must not block the call because of its priority")
+		a.True(fallback)
+
+		var name string
+		a.NoError(mock.QueryRow(ctx, "SELECT name FROM users").Scan(&name))
+		a.Equal("John", name)
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	mock, _ := NewConn()
+	mock.ExpectPing()
+	mock.ExpectQuery("SELECT .* FROM users").
+		WillReturnRows(NewRows([]string{"name"}).AddRow("John")).
+		Priority(10)
+	var name string
+	a.NoError(mock.QueryRow(ctx, "SELECT name FROM users").Scan(&name),
+		"matching expectation with higher priority is matched before the next one in order")
+	a.NoError(mock.Ping(ctx))
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestExpectationsWereMetDoesNotBlock(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+
+	mock.ExpectPing().WillDelayFor(time.Hour)
+	mock.ExpectExec("never called").WillDelayFor(time.Hour)
+
+	c, cancel := context.WithCancel(ctx)
+	pinged := make(chan error)
+	go func() { pinged <- mock.Ping(c) }()
+	time.Sleep(10 * time.Millisecond) // let Ping() to start waiting for delay
+
+	done := make(chan error)
+	go func() { done <- mock.ExpectationsWereMet() }()
+	select {
+	case err := <-done:
+		a.Error(err, "delayed Exec() was never called")
+	case <-time.After(time.Second):
+		t.Error("ExpectationsWereMet() must not wait for delayed expectations")
+	}
+	cancel()
+	a.ErrorIs(<-pinged, context.Canceled)
+}
+
+func TestExecMultipleStatementsResults(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+
+	ex := mock.ExpectExec("CREATE TABLE foo").
+		WillReturnResults(
+			NewResult("CREATE TABLE", 0),
+			NewResult("INSERT", 2),
+			NewResult("UPDATE", 1),
+		)
+	a.Zero(ex.StatementsExecuted())
+	a.Contains(ex.String(), "runs 3 statements with results")
+
+	res, err := mock.Exec(ctx, `CREATE TABLE foo(id int4);
+		INSERT INTO foo VALUES (1), (2);
+		UPDATE foo SET id = 3 WHERE id = 2`)
+	a.NoError(err)
+	a.True(res.Update(), "result of the last statement must be returned")
+	a.EqualValues(1, res.RowsAffected())
+	a.Equal(3, ex.StatementsExecuted())
+	a.NoError(mock.ExpectationsWereMet())
+
+	mock.ExpectExec("foo").WillReturnResults()
+	_, err = mock.Exec(ctx, "foo")
+	a.Error(err, "Exec must return a result")
+}
+
+func TestExecMultipleStatementsCount(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+
+	ex := mock.ExpectExec("DELETE FROM foo").
+		WillReturnResults(NewResult("DELETE", 1), NewResult("DELETE", 2))
+	_, err := mock.Exec(ctx, "DELETE FROM foo WHERE id = 1; DELETE FROM foo; DROP TABLE foo")
+	a.ErrorContains(err, "runs 3 statements, but 2 results expected")
+	_, err = mock.Exec(ctx, "DELETE FROM foo WHERE id = 1")
+	a.ErrorContains(err, "runs 1 statements, but 2 results expected")
+	a.Zero(ex.StatementsExecuted())
+	_, err = mock.Exec(ctx, "DELETE FROM foo WHERE id = 1; DELETE FROM foo")
+	a.NoError(err)
+	a.Equal(2, ex.StatementsExecuted())
+
+	ex = mock.ExpectExec("INSERT").WillReturnResult(NewResult("INSERT", 1))
+	_, err = mock.Exec(ctx, "INSERT INTO foo VALUES (1); INSERT INTO foo VALUES (2)")
+	a.NoError(err)
+	a.Equal(2, ex.StatementsExecuted(), "every statement run must be counted")
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestLabel(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+
+	ex := mock.ExpectQuery("SELECT (.+) FROM users WHERE active").
+		WillReturnRows(NewRows([]string{"id"}))
+	ex.Label("fetch active users")
+	a.Contains(ex.String(), "labeled as: 'fetch active users'")
+
+	err := mock.ExpectationsWereMet()
+	a.ErrorContains(err, "unmet expectation 'fetch active users'")
+
+	_, err = mock.Exec(ctx, "DELETE FROM users")
+	a.ErrorContains(err, "fetch active users", "next expectation must be shown with label")
+}
+
+func TestBecause(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+
+	mock.ExpectExec("INSERT INTO audit_log").
+		WillReturnResult(NewResult("INSERT", 1)).
+		Because("we always audit-log before delete")
+	mock.ExpectExec("DELETE FROM users").WillReturnResult(NewResult("DELETE", 1))
+
+	err := mock.ExpectationsWereMet()
+	a.ErrorContains(err, "remaining expectation which was not matched")
+	a.ErrorContains(err, "\nexpected because: we always audit-log before delete")
+	_, err = mock.Exec(ctx, "INSERT INTO audit_log VALUES ('delete')")
+	a.NoError(err)
+	err = mock.ExpectationsWereMet()
+	a.Error(err)
+	a.NotContains(err.Error(), "because", "the reason belongs to the unmet expectation only")
+}
+
+func TestWithQueryMatcher(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+
+	sql := "SELECT * FROM users WHERE name ~ '^(John|Jane)$'"
+	qe := mock.ExpectQuery(sql).
+		WithQueryMatcher(QueryMatcherEqual).
+		WillReturnRows(NewRows([]string{"id"}).AddRow(1))
+	a.Contains(qe.String(), "matches sql (equal)")
+	ee := mock.ExpectExec("DELETE FROM users").
+		WillReturnResult(NewResult("DELETE", 1))
+	a.Contains(ee.String(), "matches sql (regexp)")
+
+	_, err := mock.Query(ctx, sql)
+	a.NoError(err)
+	_, err = mock.Exec(ctx, "DELETE FROM users WHERE id = $1")
+	a.NoError(err, "regexp matcher should be used by default")
+	a.NoError(mock.ExpectationsWereMet())
+
+	mock.ExpectExec("DELETE FROM users").
+		WithQueryMatcher(QueryMatcherEqual).
+		WillReturnResult(NewResult("DELETE", 1))
+	_, err = mock.Exec(ctx, "DELETE FROM users WHERE id = $1")
+	a.Error(err)
+}
+
+type wrappedErr struct {
+	code string
+}
+
+func (e *wrappedErr) Error() string {
+	return "wrapped error " + e.code
+}
+
+func TestErrorChainPreserved(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+	wrapped := fmt.Errorf("wrap: %w", &wrappedErr{"42"})
+	errNoRows := fmt.Errorf("wrap: %w", pgx.ErrNoRows)
+
+	mock.ExpectQuery("SELECT").WillReturnError(errNoRows)
+	mock.ExpectQuery("SELECT").WillReturnError(errNoRows)
+	mock.ExpectExec("DELETE").WillReturnError(wrapped)
+	mock.ExpectPing().WillReturnError(wrapped)
+	mock.ExpectBegin().WillReturnError(wrapped)
+	mock.ExpectPrepare("foo", "SELECT").WillReturnError(wrapped)
+	mock.ExpectCopyFrom(pgx.Identifier{"foo"}, []string{"bar"}).WillReturnError(wrapped)
+	mock.ExpectCommit().WillReturnError(wrapped)
+	mock.ExpectRollback().WillReturnError(wrapped)
+
+	var target *wrappedErr
+	check := func(err error, method string) {
+		a.ErrorIs(err, wrapped, method)
+		a.ErrorAs(err, &target, method)
+	}
+
+	_, err := mock.Query(ctx, "SELECT 1")
+	a.ErrorIs(err, pgx.ErrNoRows, "Query()")
+	a.Same(errNoRows, err, "Query() must return the error verbatim")
+	err = mock.QueryRow(ctx, "SELECT 1").Scan()
+	a.ErrorIs(err, pgx.ErrNoRows, "QueryRow()")
+	_, err = mock.Exec(ctx, "DELETE")
+	check(err, "Exec()")
+	check(mock.Ping(ctx), "Ping()")
+	_, err = mock.Begin(ctx)
+	check(err, "Begin()")
+	_, err = mock.Prepare(ctx, "foo", "SELECT")
+	check(err, "Prepare()")
+	_, err = mock.CopyFrom(ctx, pgx.Identifier{"foo"}, []string{"bar"}, nil)
+	check(err, "CopyFrom()")
+	check(mock.Commit(ctx), "Commit()")
+	check(mock.Rollback(ctx), "Rollback()")
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+type testingTMock struct {
+	errors []string
+}
+
+func (m *testingTMock) Errorf(format string, args ...any) {
+	m.errors = append(m.errors, fmt.Sprintf(format, args...))
+}
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+
+	mock.ExpectPing()
+	tm := &testingTMock{}
+	a.False(mock.Check(tm))
+	a.Len(tm.errors, 1)
+	a.Contains(tm.errors[0], "ExpectedPing => expecting call to Ping()")
+
+	a.NoError(mock.Ping(ctx))
+	defer mock.Check(t)
+	a.True(mock.Check(tm))
+	a.Len(tm.errors, 1)
+}
+
+func TestWillTimeout(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+
+	mock.ExpectQuery("SELECT pg_sleep").WillTimeout(10 * time.Millisecond)
+	mock.ExpectExec("UPDATE").WillTimeout(time.Second)
+
+	start := time.Now()
+	_, err := mock.Query(ctx, "SELECT pg_sleep(100)")
+	a.GreaterOrEqual(time.Since(start), 10*time.Millisecond)
+	var pgErr *pgconn.PgError
+	a.ErrorAs(err, &pgErr)
+	a.Equal("57014", pgErr.Code)
+
+	c, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = mock.Exec(c, "UPDATE foo SET bar = 1")
+	a.ErrorIs(err, context.DeadlineExceeded, "context should be done before statement timeout")
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestWillReturnDeadlock(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+
+	mock.ExpectExec("UPDATE accounts").WillReturnDeadlock()
+	mock.ExpectExec("UPDATE accounts").WillReturnResult(NewResult("UPDATE", 1))
+	attempts := 0
+	var err error
+	for attempts < 3 {
+		attempts++
+		if _, err = mock.Exec(ctx, "UPDATE accounts SET balance = balance - 1"); err == nil {
+			break
+		}
+		var pgErr *pgconn.PgError
+		if !errors.As(err, &pgErr) || pgErr.Code != "40P01" {
+			break
+		}
+	}
+	a.NoError(err)
+	a.Equal(2, attempts, "deadlock must be retried")
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestExpectationsWereMetWithin(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+
+	mock.ExpectExec("INSERT INTO audit").WillReturnResult(NewResult("INSERT", 1))
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_, _ = mock.Exec(ctx, "INSERT INTO audit VALUES (1)")
+	}()
+	a.Error(mock.ExpectationsWereMet(), "background flush is not done yet")
+	a.NoError(mock.ExpectationsWereMetWithin(time.Second))
+
+	mock.ExpectPing()
+	start := time.Now()
+	err := mock.ExpectationsWereMetWithin(20 * time.Millisecond)
+	a.ErrorContains(err, "ExpectedPing => expecting call to Ping()")
+	a.GreaterOrEqual(time.Since(start), 20*time.Millisecond)
+}
+
+func TestWillReturnResultAndError(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+	errPartial := errors.New("partially applied")
+
+	e := mock.ExpectExec("UPDATE orders").WillReturnResultAndError(NewResult("UPDATE", 3), errPartial)
+	a.Contains(e.String(), "returns result: UPDATE 3")
+	res, err := mock.Exec(ctx, "UPDATE orders")
+	a.ErrorIs(err, errPartial)
+	a.EqualValues(3, res.RowsAffected(), "result must be returned along with the error")
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestMatchCount(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+	mock.MatchExpectationsInOrder(false)
+
+	optional := mock.ExpectQuery("SELECT cache")
+	optional.WillReturnRows(NewRows([]string{"v"})).Maybe()
+	repeated := mock.ExpectExec("UPDATE stats").WillReturnResult(NewResult("UPDATE", 1))
+	repeated.Times(3)
+	a.Zero(optional.MatchCount())
+
+	for i := 0; i < 2; i++ {
+		_, err := mock.Exec(ctx, "UPDATE stats")
+		a.NoError(err)
+	}
+	a.Equal(2, repeated.MatchCount())
+	a.Zero(optional.MatchCount(), "optional expectation was not exercised")
+	_, err := mock.Query(ctx, "SELECT cache")
+	a.NoError(err)
+	a.Equal(1, optional.MatchCount())
+}
+
+func TestNamedAndPositionalArgs(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+	named := "SELECT id FROM users WHERE name = @name AND age > @age"
+	positional := "SELECT id FROM users WHERE name = $1 AND age > $2"
+
+	// positional expectation, named call
+	mock.ExpectQuery("SELECT id FROM users").WithArgs("John", 18).
+		WillReturnRows(NewRows([]string{"id"}).AddRow(1))
+	_, err := mock.Query(ctx, named, pgx.NamedArgs{"age": 18, "name": "John"})
+	a.NoError(err)
+	mock.ExpectExec("UPDATE users").WithArgs("John", 42).WillReturnResult(NewResult("UPDATE", 1))
+	_, err = mock.Exec(ctx, "UPDATE users SET name = @name WHERE id = @id", pgx.NamedArgs{"id": 42, "name": "John"})
+	a.NoError(err)
+
+	// named expectation, positional call
+	mock.ExpectQuery(named).WithArgs(pgx.NamedArgs{"name": "John", "age": 18}).
+		WithQueryMatcher(QueryMatcherFunc(func(string, string) error { return nil })).
+		WillReturnRows(NewRows([]string{"id"}).AddRow(1))
+	_, err = mock.Query(ctx, positional, "John", 18)
+	a.NoError(err)
+	mock.ExpectExec(`UPDATE users SET name = @name WHERE id = @id`).
+		WithArgs(pgx.NamedArgs{"id": 42, "name": "John"}).
+		WithQueryMatcher(QueryMatcherFunc(func(string, string) error { return nil })).
+		WillReturnResult(NewResult("UPDATE", 1))
+	_, err = mock.Exec(ctx, "UPDATE users SET name = $1 WHERE id = $2", "John", 43)
+	a.Error(err, "named arguments must be compared with positional ones")
+	_, err = mock.Exec(ctx, "UPDATE users SET name = $1 WHERE id = $2", "John", 42)
+	a.NoError(err)
+
+	// named expectation with regexp pattern, positional call
+	mock.ExpectQuery(named).WithArgs(pgx.NamedArgs{"name": "John", "age": 18}).
+		WillReturnRows(NewRows([]string{"id"}).AddRow(1))
+	_, err = mock.Query(ctx, positional, "John", 18)
+	a.ErrorContains(err, "could not match actual sql", "literal pattern still must match the call sql")
+	_, err = mock.Query(ctx, named, "John", 18)
+	a.NoError(err, "pattern without metacharacters is a literal sql")
+	mock.ExpectQuery(`SELECT id FROM users WHERE name = @name AND age > @age LIMIT 10`).
+		WithArgs(pgx.NamedArgs{"name": "John", "age": 18}).
+		WithQueryMatcher(QueryMatcherEqual).
+		WillReturnRows(NewRows([]string{"id"}).AddRow(1))
+	_, err = mock.Query(ctx, "SELECT id FROM users WHERE name = @name AND age > @age LIMIT 10", "John", 18)
+	a.NoError(err)
+
+	// named expectation, named call
+	mock.ExpectQuery("SELECT id FROM users").WithArgs(pgx.NamedArgs{"name": "John", "age": 18}).
+		WillReturnRows(NewRows([]string{"id"}).AddRow(1))
+	_, err = mock.Query(ctx, named, pgx.NamedArgs{"age": 18, "name": "John"})
+	a.NoError(err)
+	a.NoError(mock.ExpectationsWereMet())
+
+	mock.ExpectQuery(`SELECT id FROM users WHERE name = @name AND age > @age.*`).
+		WithArgs(pgx.NamedArgs{"name": "John", "age": 18}).
+		WillReturnRows(NewRows([]string{"id"}).AddRow(1))
+	_, err = mock.Query(ctx, named+" LIMIT 10", "John", 18)
+	a.ErrorContains(err, "named arguments can't be bound to positional ones by sql pattern")
+}
+
+func TestCopyFromWillFailAfterRows(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+	errViolation := &pgconn.PgError{Code: "23505", Message: "duplicate key value violates unique constraint"}
+	src := make([][]any, 10)
+	for i := range src {
+		src[i] = []any{i}
+	}
+
+	ex := mock.ExpectCopyFrom(pgx.Identifier{"foo"}, []string{"bar"}).WillFailAfterRows(5, errViolation)
+	a.Contains(ex.String(), "fails after 5 rows with error")
+	rowSrc := pgx.CopyFromRows(src)
+	r, err := mock.CopyFrom(ctx, pgx.Identifier{"foo"}, []string{"bar"}, rowSrc)
+	a.ErrorIs(err, errViolation)
+	a.EqualValues(5, r)
+	a.True(rowSrc.Next())
+	v, _ := rowSrc.Values()
+	a.Equal([]any{5}, v, "only 5 rows must be consumed from the source")
+
+	mock.ExpectCopyFrom(pgx.Identifier{"foo"}, []string{"bar"}).WillFailAfterRows(50, errViolation)
+	r, err = mock.CopyFrom(ctx, pgx.Identifier{"foo"}, []string{"bar"}, pgx.CopyFromRows(src))
+	a.ErrorIs(err, errViolation)
+	a.EqualValues(10, r, "the source may have less rows")
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestWillReturnRowsFromArgs(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+
+	e := mock.ExpectQuery("INSERT INTO users").WithArgs(AnyArg()).
+		WillReturnRowsFromArgs(func(args []any) *Rows {
+			return NewRows([]string{"id", "name"}).AddRow(1, args[0])
+		})
+	e.Times(2)
+	a.Contains(e.String(), "returns rows built from arguments")
+	for _, name := range []string{"John", "Jane"} {
+		var id int
+		var returned string
+		a.NoError(mock.QueryRow(ctx, "INSERT INTO users(name) VALUES ($1) RETURNING id, name", name).Scan(&id, &returned))
+		a.Equal(name, returned)
+	}
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestWillReturnRowsFromMatch(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+
+	mock.ExpectPrepare("count_items", "SELECT count")
+	e := mock.ExpectQuery(`SELECT count\(\*\) FROM (\w+)`).
+		WillReturnRowsFromMatch(func(groups []string) *Rows {
+			return NewRows([]string{"table", "count"}).AddRow(groups[0], len(groups[0]))
+		})
+	e.Times(3)
+	a.Contains(e.String(), "returns rows built from sql capture groups")
+
+	var name string
+	var count int
+	_, err := mock.Prepare(ctx, "count_items", "SELECT count(*) FROM items")
+	a.NoError(err)
+	a.NoError(mock.QueryRow(ctx, "count_items").Scan(&name, &count))
+	a.Equal("items", name, "groups must be captured from the prepared statement SQL")
+	for _, table := range []string{"users", "orders"} {
+		a.NoError(mock.QueryRow(ctx, "SELECT count(*) FROM "+table).Scan(&name, &count))
+		a.Equal(table, name)
+		a.Equal(len(table), count)
+	}
+	a.NoError(mock.ExpectationsWereMet())
+
+	mock, _ = NewConn(QueryMatcherOption(QueryMatcherEqual))
+	mock.ExpectQuery("SELECT count(*) FROM users").WillReturnRowsFromMatch(func(groups []string) *Rows {
+		a.Nil(groups)
+		return NewRows([]string{"count"}).AddRow(1)
+	})
+	a.NoError(mock.QueryRow(ctx, "SELECT count(*) FROM users").Scan(&count))
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+type canceler interface {
+	CancelRequest(ctx context.Context) error
+}
+
+var (
+	_ canceler = (*pgconn.PgConn)(nil)
+	_ canceler = PgxConnIface(nil)
+)
+
+func TestExpectCancelRequest(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+	var c canceler = mock
+
+	a.Error(c.CancelRequest(ctx), "CancelRequest() was not expected")
+	mock.ExpectCancelRequest()
+	mock.ExpectCancelRequest().WillReturnError(errors.New("cancel failed"))
+	a.Error(mock.ExpectationsWereMet())
+	a.NoError(c.CancelRequest(ctx))
+	a.ErrorContains(c.CancelRequest(ctx), "cancel failed")
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestAndThen(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+
+	mock.ExpectBegin().AndThen().
+		ExpectExec("UPDATE accounts").WillReturnResult(NewResult("UPDATE", 1)).AndThen().
+		ExpectQuery("SELECT balance").WillReturnRows(NewRows([]string{"balance"}).AddRow(10)).AndThen().
+		ExpectCommit()
+
+	tx, err := mock.Begin(ctx)
+	a.NoError(err)
+	_, err = tx.Exec(ctx, "UPDATE accounts SET balance = 10")
+	a.NoError(err)
+	var balance int
+	a.NoError(tx.QueryRow(ctx, "SELECT balance FROM accounts").Scan(&balance))
+	a.Equal(10, balance)
+	a.NoError(tx.Commit(ctx))
+	a.NoError(mock.ExpectationsWereMet())
+
+	pool, _ := NewPool()
+	pool.ExpectPing().AndThen().ExpectPing()
+	a.NoError(pool.Ping(ctx))
+	a.NoError(pool.Ping(ctx))
+	a.NoError(pool.ExpectationsWereMet())
+}
+
+func TestWithOptionalArgs(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+	ctx := context.Background()
+	mock.MatchExpectationsInOrder(false)
+	mock.ExpectQuery("SELECT name FROM users").
+		WithOptionalArgs(AnyArg()).
+		WillReturnRows(NewRows([]string{"name"}).AddRow("john")).
+		Times(2)
+	mock.ExpectExec("DELETE FROM users").
+		WithOptionalArgs(42).
+		WillReturnResult(NewResult("DELETE", 1))
+
+	rows, err := mock.Query(ctx, "SELECT name FROM users")
+	a.NoError(err)
+	rows.Close()
+	rows, err = mock.Query(ctx, "SELECT name FROM users WHERE name = $1", "john")
+	a.NoError(err)
+	rows.Close()
+
+	_, err = mock.Exec(ctx, "DELETE FROM users WHERE id = $1", 7)
+	a.ErrorContains(err, "argument 0 expected [int - 42] does not match actual [int - 7]")
+	_, err = mock.Exec(ctx, "DELETE FROM users WHERE id = $1 AND $2", 42, true)
+	a.ErrorContains(err, "expected 1, but got 2 arguments")
+	_, err = mock.Exec(ctx, "DELETE FROM users WHERE id = $1", 42)
+	a.NoError(err)
+	a.NoError(mock.ExpectationsWereMet())
+	a.Contains(mock.ExpectExec("DELETE").WithOptionalArgs(42).String(), "is without arguments or with arguments:")
+}
+
+func TestMustNotMatch(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+	mock.ExpectQuery("SELECT .+ FROM users").
+		MustNotMatch(`(?i)SELECT\s+\*`).
+		WillReturnRows(NewRows([]string{"id"}))
+	mock.ExpectExec("DELETE FROM users").
+		MustNotMatch(`^DELETE FROM users$`).
+		MustNotMatch(`(?i)WHERE\s+true`).
+		WillReturnResult(NewResult("DELETE", 1))
+
+	_, err := mock.Query(ctx, "SELECT * FROM users")
+	a.ErrorContains(err, `actual sql: "SELECT * FROM users" matches forbidden pattern "(?i)SELECT\s+\*"`)
+	rows, err := mock.Query(ctx, "SELECT id FROM users")
+	a.NoError(err)
+	rows.Close()
+	_, err = mock.Exec(ctx, "DELETE FROM users")
+	a.ErrorContains(err, "matches forbidden pattern")
+	_, err = mock.Exec(ctx, "DELETE FROM users WHERE TRUE")
+	a.ErrorContains(err, "matches forbidden pattern")
+	_, err = mock.Exec(ctx, "DELETE FROM users WHERE id = 1")
+	a.NoError(err)
+	a.NoError(mock.ExpectationsWereMet())
+	a.Panics(func() { mock.ExpectExec("DELETE").MustNotMatch("(") })
+}
+
+func TestMustHaveLimit(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	cases := []struct {
+		sql   string
+		match bool
+	}{
+		{"SELECT id FROM users LIMIT 10", true},
+		{"SELECT id FROM users ORDER BY id LIMIT $1 OFFSET $2;", true},
+		{"select id from users offset 20 limit 10", true},
+		{"SELECT id FROM users FETCH FIRST 5 ROWS ONLY", true},
+		{"SELECT id FROM jobs LIMIT 1 FOR UPDATE SKIP LOCKED", true},
+		{"SELECT id FROM users", false},
+		{"SELECT id FROM users LIMIT ALL", false},
+		{"SELECT id FROM (SELECT id FROM users LIMIT 10) u", false},
+		{"SELECT id FROM users WHERE note = 'LIMIT 10' AND active", false},
+	}
+	for _, c := range cases {
+		mock, _ := NewConn()
+		e := mock.ExpectQuery("(?i)SELECT").MustHaveLimit().WillReturnRows(NewRows([]string{"id"}))
+		a.Contains(e.String(), "must end with LIMIT clause")
+		_, err := mock.Query(ctx, c.sql)
+		if c.match {
+			a.NoError(err, c.sql)
+		} else {
+			a.ErrorContains(err, "does not end with LIMIT clause", c.sql)
+		}
+	}
+}
+
+func TestExpectSingleRow(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+	rows := NewRows([]string{"id"}).AddRow(1).AddRow(2)
+	mock.ExpectQuery("SELECT id FROM users").WillReturnRows(rows).ExpectSingleRow()
+	mock.ExpectQuery("SELECT id FROM users").WillReturnRows(rows).ExpectSingleRow()
+	mock.ExpectQuery("SELECT id FROM users").WillReturnRows(NewRows([]string{"id"}).AddRow(1)).ExpectSingleRow()
+
+	var id int
+	a.ErrorContains(mock.QueryRow(ctx, "SELECT id FROM users").Scan(&id),
+		"QueryRow: single row expected, but query returned 2 rows: SELECT id FROM users")
+	rs, err := mock.Query(ctx, "SELECT id FROM users")
+	a.NoError(err, "Query() must not be affected")
+	rs.Close()
+	a.NoError(mock.QueryRow(ctx, "SELECT id FROM users").Scan(&id))
+	a.Equal(1, id)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestExpectationStringers(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+	for _, e := range []interface {
+		CallModifier
+		fmt.Stringer
+	}{
+		mock.ExpectClose(),
+		mock.ExpectBegin(),
+		mock.ExpectCommit(),
+		mock.ExpectRollback(),
+		mock.ExpectExec("UPDATE"),
+		mock.ExpectQuery("SELECT"),
+		mock.ExpectPrepare("stmt", "SELECT"),
+		mock.ExpectPing(),
+		mock.ExpectCancelRequest(),
+		mock.ExpectAcquire(),
+		mock.ExpectCopyFrom(pgx.Identifier{"t"}, []string{"id"}),
+		mock.ExpectSendBatchLen(1),
+		mock.ExpectReset(),
+	} {
+		e.WillReturnError(errors.New("failed"))
+		msg := e.String()
+		a.Regexp(`^Expected\w+ => expecting call to [\w.]+\(\)`, msg)
+		a.Contains(msg, "\t- returns error: failed\n", "%T must describe the common expectation part", e)
+	}
+}
+
+func TestTotalRowsCopied(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+	table, columns := pgx.Identifier{"users"}, []string{"name"}
+	mock.ExpectCopyFrom(table, columns).WillReturnResult(100).Times(2)
+	mock.ExpectCopyFrom(table, columns).WillReturnResult(42)
+	mock.ExpectCopyFrom(table, columns).WillReturnResult(10).WillReturnError(errors.New("copy failed"))
+	mock.ExpectCopyFrom(table, columns).WillFailAfterRows(5, errors.New("copy failed"))
+
+	a.Zero(mock.TotalRowsCopied())
+	for i := 0; i < 5; i++ {
+		_, _ = mock.CopyFrom(ctx, table, columns, pgx.CopyFromRows([][]any{{"john"}}))
+	}
+	a.EqualValues(242, mock.TotalRowsCopied(), "failed calls must not be counted")
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestRetryable(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+	// retry wrapper giving every attempt more time
+	retry := func(attempts int) (err error) {
+		for i := 1; i <= attempts; i++ {
+			c, cancel := context.WithTimeout(ctx, time.Duration(i*i)*10*time.Millisecond)
+			_, err = mock.Exec(c, "UPDATE orders SET status = 'paid'")
+			cancel()
+			if err == nil {
+				return nil
+			}
+		}
+		return err
+	}
+
+	ex := mock.ExpectExec("UPDATE orders").
+		WillReturnResult(NewResult("UPDATE", 1))
+	ex.WillDelayFor(50 * time.Millisecond).Retryable(2)
+	a.Contains(ex.String(), "may be retried up to 2 times until succeeded")
+	mock.ExpectExec("UPDATE orders").WillReturnResult(NewResult("UPDATE", 1))
+	a.NoError(retry(3))
+	a.Equal(3, ex.MatchCount())
+	a.Error(mock.ExpectationsWereMet(), "succeeded expectation must not match anymore")
+	_, err := mock.Exec(ctx, "UPDATE orders SET status = 'paid'")
+	a.NoError(err)
+	a.NoError(mock.ExpectationsWereMet())
+
+	mock.ExpectExec("UPDATE orders").
+		WillReturnResult(NewResult("UPDATE", 1)).
+		WillDelayFor(time.Second).
+		Retryable(1)
+	a.ErrorIs(retry(2), context.DeadlineExceeded)
+	_, err = mock.Exec(ctx, "UPDATE orders SET status = 'paid'")
+	a.Error(err, "attempts are exhausted")
+	a.ErrorContains(mock.ExpectationsWereMet(), "expectation was retried without success")
+}
@@ -0,0 +1,92 @@
+package pgxmock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+type operationKey struct{}
+
+type roleKey struct{}
+
+// Database roles queries are routed to, see ContextWithRole
+const (
+	RolePrimary = "primary"
+	RoleReplica = "replica"
+)
+
+// ContextWithOperation returns a copy of ctx tagged with the operation name,
+// which is matched by the WithOperation() of query and exec expectations.
+// Use OperationContextKey option if the application tags contexts itself.
+func ContextWithOperation(ctx context.Context, operation string) context.Context {
+	return context.WithValue(ctx, operationKey{}, operation)
+}
+
+// operation returns the operation the call context is tagged with
+func (c *pgxmock) operation(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	var key any = operationKey{}
+	if c.operationKey != nil {
+		key = c.operationKey
+	}
+	switch op := ctx.Value(key).(type) {
+	case nil:
+		return "", false
+	case string:
+		return op, true
+	default:
+		return fmt.Sprint(op), true
+	}
+}
+
+// operationMatches checks whether the call context is tagged with
+// the operation expected, if any
+func (c *pgxmock) operationMatches(ctx context.Context, e *queryBasedExpectation) error {
+	if e.operation == "" {
+		return nil
+	}
+	op, ok := c.operation(ctx)
+	if !ok {
+		return fmt.Errorf("call context is not tagged with operation, expected operation is '%s'", e.operation)
+	}
+	if op != e.operation {
+		return fmt.Errorf("operation '%s' was not expected, expected operation is '%s'", op, e.operation)
+	}
+	return nil
+}
+
+// ContextWithRole returns a copy of ctx tagged with the database role, e.g.
+// RoleReplica, the call is routed to. Queries with untagged context are
+// routed to RolePrimary. See ExpectQueryOnReplica.
+func ContextWithRole(ctx context.Context, role string) context.Context {
+	return context.WithValue(ctx, roleKey{}, role)
+}
+
+// roleMatches checks whether the call is routed to the role expected,
+// expectations without a role match the primary only
+func roleMatches(ctx context.Context, e *queryBasedExpectation) error {
+	expected, actual := e.role, RolePrimary
+	if expected == "" {
+		expected = RolePrimary
+	}
+	if ctx != nil {
+		if role, ok := ctx.Value(roleKey{}).(string); ok && role != "" {
+			actual = role
+		}
+	}
+	if actual != expected {
+		return fmt.Errorf("call routed to role '%s' was not expected, expected role is '%s'", actual, expected)
+	}
+	return nil
+}
+
+// contextMatches checks the call context with the matcher of the expectation, if any
+func contextMatches(ctx context.Context, e *queryBasedExpectation) error {
+	if e.contextMatcher == nil || e.contextMatcher(ctx) {
+		return nil
+	}
+	return errors.New("call context does not match the expectation")
+}
@@ -0,0 +1,115 @@
+package pgxmock
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithOperation(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+	mock.MatchExpectationsInOrder(false)
+	ctx := context.Background()
+
+	mock.ExpectExec("UPDATE orders").WithOperation("CancelOrder").WillReturnResult(NewResult("UPDATE", 2))
+	mock.ExpectExec("UPDATE orders").WithOperation("CreateOrder").WillReturnResult(NewResult("UPDATE", 1))
+	mock.ExpectQuery("SELECT id").WithOperation("CreateOrder").WillReturnRows(NewRows([]string{"id"}).AddRow(1))
+
+	_, err := mock.Exec(ctx, "UPDATE orders")
+	a.ErrorContains(err, "not tagged with operation")
+
+	res, err := mock.Exec(ContextWithOperation(ctx, "CreateOrder"), "UPDATE orders")
+	a.NoError(err)
+	a.EqualValues(1, res.RowsAffected())
+	res, err = mock.Exec(ContextWithOperation(ctx, "CancelOrder"), "UPDATE orders")
+	a.NoError(err)
+	a.EqualValues(2, res.RowsAffected())
+
+	_, err = mock.Query(ContextWithOperation(ctx, "CancelOrder"), "SELECT id")
+	a.ErrorContains(err, "operation 'CancelOrder' was not expected")
+	rows, err := mock.Query(ContextWithOperation(ctx, "CreateOrder"), "SELECT id")
+	a.NoError(err)
+	rows.Close()
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+type appOperation string
+
+func TestOperationContextKey(t *testing.T) {
+	t.Parallel()
+	type ctxKey struct{}
+	mock, _ := NewConn(OperationContextKey(ctxKey{}))
+	a := assert.New(t)
+
+	e := mock.ExpectExec("DELETE FROM orders").WithOperation("DeleteOrder").WillReturnResult(NewResult("DELETE", 1))
+	a.Contains(e.String(), "is tagged with operation: 'DeleteOrder'")
+	_, err := mock.Exec(ContextWithOperation(context.Background(), "DeleteOrder"), "DELETE FROM orders")
+	a.Error(err, "default key must not be used when custom key is registered")
+	ctx := context.WithValue(context.Background(), ctxKey{}, appOperation("DeleteOrder"))
+	_, err = mock.Exec(ctx, "DELETE FROM orders")
+	a.NoError(err)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestExpectQueryOnReplica(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewPool()
+	mock.MatchExpectationsInOrder(false)
+	a := assert.New(t)
+
+	mock.ExpectQuery("SELECT name").WillReturnRows(NewRows([]string{"name"}).AddRow("fresh"))
+	e := mock.ExpectQueryOnReplica("SELECT name").WillReturnRows(NewRows([]string{"name"}).AddRow("stale"))
+	a.Contains(e.String(), "is routed to role: 'replica'")
+
+	var name string
+	a.NoError(mock.QueryRow(ContextWithRole(ctx, RoleReplica), "SELECT name FROM users").Scan(&name))
+	a.Equal("stale", name)
+	a.NoError(mock.QueryRow(ctx, "SELECT name FROM users").Scan(&name))
+	a.Equal("fresh", name)
+	a.NoError(mock.ExpectationsWereMet())
+
+	mock.ExpectQuery("SELECT name").WillReturnRows(NewRows([]string{"name"}).AddRow("fresh"))
+	err := mock.QueryRow(ContextWithRole(ctx, RoleReplica), "SELECT name FROM users").Scan(&name)
+	a.ErrorContains(err, "call routed to role 'replica' was not expected, expected role is 'primary'")
+	a.NoError(mock.QueryRow(ContextWithRole(ctx, RolePrimary), "SELECT name FROM users").Scan(&name))
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+// span mimics a tracing span stored in the context by instrumentation
+type span struct{ traceID string }
+
+type spanKey struct{}
+
+func spanFromContext(ctx context.Context) (span, bool) {
+	s, ok := ctx.Value(spanKey{}).(span)
+	return s, ok
+}
+
+func TestWithContextMatcher(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+
+	hasSpan := func(ctx context.Context) bool {
+		s, ok := spanFromContext(ctx)
+		return ok && s.traceID == "4bf92f3577b34da6"
+	}
+	e := mock.ExpectQuery("SELECT id").WithContextMatcher(hasSpan).WillReturnRows(NewRows([]string{"id"}).AddRow(1))
+	a.Contains(e.String(), "is with the call context matching")
+	mock.ExpectExec("DELETE").WithContextMatcher(hasSpan).WillReturnResult(NewResult("DELETE", 1))
+
+	spanCtx := context.WithValue(ctx, spanKey{}, span{traceID: "4bf92f3577b34da6"})
+	_, err := mock.Query(ctx, "SELECT id FROM orders")
+	a.ErrorContains(err, "call context does not match the expectation")
+	rows, err := mock.Query(spanCtx, "SELECT id FROM orders")
+	a.NoError(err)
+	rows.Close()
+	_, err = mock.Exec(ctx, "DELETE FROM orders")
+	a.Error(err)
+	_, err = mock.Exec(spanCtx, "DELETE FROM orders")
+	a.NoError(err)
+	a.NoError(mock.ExpectationsWereMet())
+}
@@ -1,5 +1,12 @@
 package pgxmock
 
+import (
+	"time"
+
+	pgx "github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
 // QueryMatcherOption allows to customize SQL query matcher
 // and match SQL query strings in more sophisticated ways.
 // The default QueryMatcher is QueryMatcherRegexp.
@@ -9,3 +16,150 @@ func QueryMatcherOption(queryMatcher QueryMatcher) func(*pgxmock) error {
 		return nil
 	}
 }
+
+// ValidateArgTypesFromCasts allows to check whether the arguments of
+// Query() and Exec() calls are compatible with explicit type casts
+// in the SQL, e.g. passing a string for $1::int produces an error.
+func ValidateArgTypesFromCasts(validate bool) func(*pgxmock) error {
+	return func(s *pgxmock) error {
+		s.validateArgTypes = validate
+		return nil
+	}
+}
+
+// ValidatePlaceholderCount allows to check whether the number of positional
+// placeholders, e.g. $1, of Query() and Exec() calls matches the number of
+// arguments passed, regardless of the expectation arguments, the same as
+// PostgreSQL would do. Placeholders must be numbered without gaps.
+func ValidatePlaceholderCount(validate bool) func(*pgxmock) error {
+	return func(s *pgxmock) error {
+		s.validatePlaceholders = validate
+		return nil
+	}
+}
+
+// WithDefaultQueryTimeout allows to apply a timeout to Query() and Exec()
+// calls whose context has no deadline, the same way some applications
+// configure default statement timeouts. Delayed expectations exceeding
+// the timeout will return context.DeadlineExceeded error.
+func WithDefaultQueryTimeout(timeout time.Duration) func(*pgxmock) error {
+	return func(s *pgxmock) error {
+		s.queryTimeout = timeout
+		return nil
+	}
+}
+
+// OperationContextKey allows to specify the context key the application
+// uses to tag call contexts with an operation name. Values are matched by
+// WithOperation() of expectations. By default ContextWithOperation is used.
+func OperationContextKey(key any) func(*pgxmock) error {
+	return func(s *pgxmock) error {
+		s.operationKey = key
+		return nil
+	}
+}
+
+// ConnConfigOption allows to specify the configuration returned by
+// Config() of the connection mock, e.g. to test code that derives
+// logging fields from the host or database name
+func ConnConfigOption(config *pgx.ConnConfig) func(*pgxmock) error {
+	return func(s *pgxmock) error {
+		s.connConfig = config
+		return nil
+	}
+}
+
+// PanicOnUnexpected allows to panic with the SQL and the stack trace
+// if a Query(), QueryRow(), Exec() or Ping() call does not match any
+// expectation, instead of returning an error that might be ignored
+// by the code under test
+func PanicOnUnexpected(panicOnUnexpected bool) func(*pgxmock) error {
+	return func(s *pgxmock) error {
+		s.panicOnUnexpected = panicOnUnexpected
+		return nil
+	}
+}
+
+// DisallowSimpleProtocol allows to fail Query() and Exec() calls using
+// pgx.QueryExecModeSimpleProtocol, either passed as an argument or set as
+// the DefaultQueryExecMode of the configuration, see ConnConfigOption
+func DisallowSimpleProtocol(disallow bool) func(*pgxmock) error {
+	return func(s *pgxmock) error {
+		s.disallowSimpleProtocol = disallow
+		return nil
+	}
+}
+
+// WarningHandler allows to receive warnings about suspicious usage detected
+// by the mock, e.g. the same map or slice argument shared by queued queries
+// of a batch. By default warnings are ignored, mocks created by NewConnT
+// and NewPoolT log warnings with testing.TB.Logf unless the handler is set.
+func WarningHandler(handler func(msg string)) func(*pgxmock) error {
+	return func(s *pgxmock) error {
+		s.warningHandler = handler
+		return nil
+	}
+}
+
+// UnorderedBatches allows SendBatch() calls to match expected batches by
+// the content of queued queries regardless of the order expectations were
+// set, even if MatchExpectationsInOrder is enabled. Useful for code sending
+// several batches concurrently, e.g. pipelining through the pool. Note that
+// the same as pgx.BatchResults every mocked result must be read by a single
+// goroutine at a time.
+func UnorderedBatches(unordered bool) func(*pgxmock) error {
+	return func(s *pgxmock) error {
+		s.unorderedBatches = unordered
+		return nil
+	}
+}
+
+// TypeMapOption allows to set up the type map returned by TypeMap(), e.g.
+// register custom types the same way as for the connection in AfterConnect.
+// Every clone gets its own type map set up by the same function.
+func TypeMapOption(setup func(typeMap *pgtype.Map)) func(*pgxmock) error {
+	return func(s *pgxmock) error {
+		s.typeMapSetup = setup
+		return nil
+	}
+}
+
+// NullScanBehavior defines how NULL is scanned into types not able to hold it
+type NullScanBehavior int
+
+const (
+	// NullScanError returns an error the same way pgx does
+	NullScanError NullScanBehavior = iota
+	// NullScanZero sets the zero value, e.g. 0 or ""
+	NullScanZero
+)
+
+// NullScanMode allows to specify how NULL values are scanned into types
+// not able to hold them, e.g. int or string. NullScanError is the default.
+// Pointers, interfaces, slices, maps and scanners get NULL in any mode.
+func NullScanMode(mode NullScanBehavior) func(*pgxmock) error {
+	return func(s *pgxmock) error {
+		s.nullScanMode = mode
+		return nil
+	}
+}
+
+// MaxQueryLength allows to fail Query() and Exec() calls with SQL longer than n
+// bytes, e.g. produced by a query builder from a huge list of values.
+// Zero or negative n means no limit.
+func MaxQueryLength(n int) func(*pgxmock) error {
+	return func(s *pgxmock) error {
+		s.maxQueryLength = n
+		return nil
+	}
+}
+
+// MaxArgCount allows to fail Query() and Exec() calls with more than n
+// arguments, counted after rewriting by pgx.QueryRewriter, e.g. pgx.NamedArgs.
+// Zero or negative n means no limit.
+func MaxArgCount(n int) func(*pgxmock) error {
+	return func(s *pgxmock) error {
+		s.maxArgCount = n
+		return nil
+	}
+}
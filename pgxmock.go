@@ -11,12 +11,22 @@ The driver allows to mock any pgx driver method behavior.
 package pgxmock
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
+	"regexp"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	pgx "github.com/jackc/pgx/v5"
 	pgconn "github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 	pgxpool "github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -27,27 +37,102 @@ type Expecter interface {
 	// ExpectationsWereMet checks whether all queued expectations
 	// were met in order (unless MatchExpectationsInOrder set to false).
 	// If any of them was not met - an error is returned.
+	// It never blocks waiting for delayed expectations to be triggered.
 	ExpectationsWereMet() error
 
+	// ExpectationsWereMetWithin polls ExpectationsWereMet until all
+	// expectations are met or the timeout elapses. Useful when queries
+	// are issued asynchronously, e.g. by background workers. On timeout
+	// the last error of ExpectationsWereMet is returned.
+	ExpectationsWereMetWithin(timeout time.Duration) error
+
+	// Check calls ExpectationsWereMet and reports the error if any
+	// using t, e.g. *testing.T or any other TestingT implementation.
+	// Returns true if all expectations were met.
+	Check(t TestingT) bool
+
+	// AssertNoOpenTransactions checks whether every transaction started by
+	// Begin() or BeginTx() was finished by Commit() or Rollback().
+	// Useful to catch early returns skipping the rollback.
+	AssertNoOpenTransactions() error
+
+	// TotalRowsCopied returns the number of rows copied by all CopyFrom()
+	// calls succeeded so far, e.g. to check the total of chunked loads.
+	TotalRowsCopied() int64
+
+	// LoadExpectations sets expectations declared by specs in the same order,
+	// an error is returned for an unsupported method, specs loaded before
+	// remain expected
+	LoadExpectations(specs []ExpectationSpec) error
+
 	// ExpectClose queues an expectation for this database
 	// action to be triggered. The *ExpectedClose allows
 	// to mock database response
 	ExpectClose() *ExpectedClose
 
 	// ExpectPrepare expects Prepare() to be called with expectedSQL query.
-	// the *ExpectedPrepare allows to mock database response.
+	// the *ExpectedPrepare allows to mock database response, e.g. prepare-time
+	// failures with WillReturnError distinct from execution failures.
 	// Note that you may expect Query() or Exec() on the *ExpectedPrepare
 	// statement to prevent repeating expectedSQL
 	ExpectPrepare(expectedStmtName, expectedSQL string) *ExpectedPrepare
 
+	// ExpectPrepareQuery expects Prepare() of the statement followed by
+	// Query() or QueryRow() called with the statement name. The returned
+	// *ExpectedQuery allows to mock the query response, e.g. WillReturnRows.
+	// It is a shortcut for ExpectPrepare(name, sql).ExpectQuery() with the
+	// name matched exactly, the query matches only after Prepare() succeeded.
+	ExpectPrepareQuery(expectedStmtName, expectedSQL string) *ExpectedQuery
+
 	// ExpectQuery expects Query() or QueryRow() to be called with expectedSQL query.
 	// the *ExpectedQuery allows to mock database response.
+	// If Query() is called with a prepared statement name, expectedSQL may
+	// match either the name or the SQL of the prepared statement.
 	ExpectQuery(expectedSQL string) *ExpectedQuery
 
+	// ExpectQueryE is the same as ExpectQuery, but returns an error if
+	// expectedSQL is not valid for the query matcher, e.g. a bad regexp.
+	// The expectation is not set in such case.
+	ExpectQueryE(expectedSQL string) (*ExpectedQuery, error)
+
 	// ExpectExec expects Exec() to be called with expectedSQL query.
-	// the *ExpectedExec allows to mock database response
+	// the *ExpectedExec allows to mock database response.
+	// If Exec() is called with a prepared statement name, expectedSQL may
+	// match either the name or the SQL of the prepared statement.
 	ExpectExec(expectedSQL string) *ExpectedExec
 
+	// ExpectExecE is the same as ExpectExec, but returns an error if
+	// expectedSQL is not valid for the query matcher, e.g. a bad regexp.
+	// The expectation is not set in such case.
+	ExpectExecE(expectedSQL string) (*ExpectedExec, error)
+
+	// ExpectAnyExec expects any number of Exec() calls not matched by
+	// other expectations, even in order mode. Such calls return an
+	// empty result. Useful for tests focused on a single interaction.
+	ExpectAnyExec() *ExpectedExec
+
+	// ExpectSearchPath expects SET search_path statement to be executed
+	// by Exec() with the given schemas, in the same order. The syntax
+	// variant and quoting of schema names are not important.
+	ExpectSearchPath(schemas ...string) *ExpectedExec
+
+	// ExpectQueryOnReplica expects Query() or QueryRow() to be called with
+	// expectedSQL and the context tagged with RoleReplica by ContextWithRole,
+	// e.g. to return stale rows simulating replica lag. Queries expected by
+	// ExpectQuery are routed to the primary and do not match such calls.
+	ExpectQueryOnReplica(expectedSQL string) *ExpectedQuery
+
+	// ExpectExecMulti expects Exec() to be called with SQL of multiple
+	// statements, e.g. a migration script. The SQL is split on semicolons
+	// outside of literals and each statement is matched against the
+	// pattern with the same index using the query matcher of the mock.
+	ExpectExecMulti(expectedSQL ...string) *ExpectedExec
+
+	// ExpectAnyQuery expects any number of Query() or QueryRow() calls
+	// not matched by other expectations, even in order mode. Such calls
+	// return empty rows.
+	ExpectAnyQuery() *ExpectedQuery
+
 	// ExpectBegin expects pgx.Conn.Begin to be called.
 	// the *ExpectedBegin allows to mock database response
 	ExpectBegin() *ExpectedBegin
@@ -60,22 +145,42 @@ type Expecter interface {
 	// the *ExpectedCommit allows to mock database response
 	ExpectCommit() *ExpectedCommit
 
+	// ExpectAcquire expects pgxpool.Acquire() to be called. Since
+	// *pgxpool.Conn cannot be mocked, the call may only fail, use
+	// WillReturnError or WillTimeoutAcquire to simulate pool exhaustion
+	ExpectAcquire() *ExpectedAcquire
+
 	// ExpectReset expects pgxpool.Reset() to be called.
 	// The *ExpectedReset allows to mock database response
 	ExpectReset() *ExpectedReset
 
 	// ExpectRollback expects pgx.Tx.Rollback to be called.
-	// the *ExpectedRollback allows to mock database response
+	// the *ExpectedRollback allows to mock database response.
+	// Rollback() called with a done context, e.g. in a deferred cleanup,
+	// fulfills the expectation and returns the context error as pgx does.
 	ExpectRollback() *ExpectedRollback
 
 	// ExpectPing expected Ping() to be called.
 	// The *ExpectedPing allows to mock database response
 	ExpectPing() *ExpectedPing
 
+	// ExpectCancelRequest expects CancelRequest() to be called.
+	// The *ExpectedCancelRequest allows to mock database response
+	ExpectCancelRequest() *ExpectedCancelRequest
+
 	// ExpectCopyFrom expects pgx.CopyFrom to be called.
 	// The *ExpectCopyFrom allows to mock database response
 	ExpectCopyFrom(expectedTableName pgx.Identifier, expectedColumns []string) *ExpectedCopyFrom
 
+	// ExpectSendBatch expects pgx.SendBatch to be called with queued
+	// queries matching expectedBatch elements in the same order.
+	// The *ExpectedBatch allows to mock database response
+	ExpectSendBatch(expectedBatch *Batch) *ExpectedBatch
+
+	// ExpectSendBatchLen expects pgx.SendBatch to be called with exactly
+	// n queued queries of any content. Results of queries are empty.
+	ExpectSendBatchLen(n int) *ExpectedBatch
+
 	// MatchExpectationsInOrder gives an option whether to match all
 	// expectations in the order they were set or not.
 	//
@@ -89,6 +194,11 @@ type Expecter interface {
 	// expectations will be expected in order
 	MatchExpectationsInOrder(bool)
 
+	// RequireAllQueriesInTx gives an option whether Query(), QueryRow()
+	// and Exec() calls fail if no transaction started by Begin() is open,
+	// e.g. to enforce every data operation to be transactional.
+	RequireAllQueriesInTx(bool)
+
 	// NewRows allows Rows to be created from a []string slice.
 	NewRows(columns []string) *Rows
 
@@ -100,6 +210,12 @@ type Expecter interface {
 	NewColumn(name string) *pgconn.FieldDescription
 }
 
+// TestingT is an interface wrapper around *testing.T, *testing.B
+// and some other testing frameworks test objects
+type TestingT interface {
+	Errorf(format string, args ...any)
+}
+
 // PgxCommonIface represents common interface for all pgx connection interfaces:
 // pgxpool.Pool, pgx.Conn and pgx.Tx
 type PgxCommonIface interface {
@@ -109,34 +225,99 @@ type PgxCommonIface interface {
 	Ping(context.Context) error
 }
 
-// PgxConnIface represents pgx.Conn specific interface
+// PgxConnIface represents pgx.Conn specific interface implemented by the mock
+// returned from NewConn. Production code may accept a narrower interface of
+// the pgx.Conn methods it uses, satisfied both by *pgx.Conn and PgxConnIface.
 type PgxConnIface interface {
 	PgxCommonIface
 	Close(ctx context.Context) error
 	Deallocate(ctx context.Context, name string) error
 	Config() *pgx.ConnConfig
 	PgConn() *pgconn.PgConn
+	// CancelRequest simulates pgconn.PgConn.CancelRequest. Since PgConn()
+	// returns the concrete type, code under test may depend on an interface
+	// with CancelRequest satisfied by both *pgconn.PgConn and PgxConnIface.
+	CancelRequest(ctx context.Context) error
+	// TypeMap returns the type map set up by TypeMapOption or a default one,
+	// types registered are used to scan values of columns with type OIDs.
+	// Types registered directly in the map are not registered for clones.
+	TypeMap() *pgtype.Map
+	// IsClosed reports if the connection has been closed
+	// by the successfully matched Close() call.
+	IsClosed() bool
+	// Clone creates a new mock connection with the same configuration,
+	// e.g. query matcher and options, but with no expectations.
+	// Useful for parallel subtests sharing the setup.
+	Clone() (PgxConnIface, error)
+}
+
+// PgxPoolConnIface represents pgxpool.Conn specific interface implemented
+// by the mock connection returned from AsConn() of the pool mock.
+type PgxPoolConnIface interface {
+	PgxConnIface
+	// Release returns the connection to the pool, subsequent
+	// calls do nothing the same as for *pgxpool.Conn.
+	Release()
 }
 
-// PgxPoolIface represents pgxpool.Pool specific interface
+// PgxPoolIface represents pgxpool.Pool specific interface implemented by the
+// mock returned from NewPool. Production code may accept a narrower interface
+// of the pgxpool.Pool methods it uses, satisfied both by *pgxpool.Pool and PgxPoolIface.
 type PgxPoolIface interface {
 	PgxCommonIface
 	Acquire(ctx context.Context) (*pgxpool.Conn, error)
 	AcquireAllIdle(ctx context.Context) []*pgxpool.Conn
 	AcquireFunc(ctx context.Context, f func(*pgxpool.Conn) error) error
-	AsConn() PgxConnIface
+	// AsConn is similar to Acquire but returns proper mocking interface.
+	// The connection implements Release() the same as *pgxpool.Conn.
+	// Every call counts as an acquired connection, see AssertBalancedPool.
+	AsConn() PgxPoolConnIface
+	// AssertBalancedPool checks whether every connection acquired by
+	// AsConn() was released, regardless of expectations. Note that AsConn()
+	// without Release() fails the check. Acquire() and AcquireFunc() never
+	// return a connection, so they are not counted.
+	AssertBalancedPool() error
+	// ExpectPoolClose expects Close() of the pool to be called exactly once,
+	// ExpectationsWereMet fails if the pool was not closed or closed again.
+	ExpectPoolClose() *ExpectedClose
 	Close()
 	Stat() *pgxpool.Stat
 	Reset()
 	Config() *pgxpool.Config
+	// Clone creates a new mock pool with the same configuration,
+	// e.g. query matcher and options, but with no expectations.
+	// Useful for parallel subtests sharing the setup.
+	Clone() (PgxPoolIface, error)
 }
 
 type pgxmock struct {
-	ordered      bool
-	queryMatcher QueryMatcher
-	expectations []expectation
+	ordered                bool
+	queryMatcher           QueryMatcher
+	expectations           []expectation
+	validateArgTypes       bool
+	validatePlaceholders   bool
+	prepared               *sync.Map // prepared statement names mapped to SQL
+	queryTimeout           time.Duration
+	closed                 *atomic.Bool
+	operationKey           any // context key of the operation name, see OperationContextKey
+	openTx                 *atomic.Int32
+	rowsCopied             *atomic.Int64 // rows copied by successful CopyFrom() calls
+	acquired               *atomic.Int32 // pool connections acquired, but not released
+	connConfig             *pgx.ConnConfig
+	panicOnUnexpected      bool
+	disallowSimpleProtocol bool
+	warningHandler         func(msg string) // see WarningHandler
+	unorderedBatches       bool
+	typeMap                *pgtype.Map
+	typeMapSetup           func(typeMap *pgtype.Map) // see TypeMapOption
+	nullScanMode           NullScanBehavior
+	requireTx              bool // see RequireAllQueriesInTx
+	maxQueryLength         int
+	maxArgCount            int
 }
 
+var errConnClosed = errors.New("conn closed")
+
 func (c *pgxmock) AcquireAllIdle(_ context.Context) []*pgxpool.Conn {
 	return []*pgxpool.Conn{}
 }
@@ -148,7 +329,7 @@ func (c *pgxmock) AcquireFunc(_ context.Context, _ func(*pgxpool.Conn) error) er
 // region Expectations
 func (c *pgxmock) ExpectClose() *ExpectedClose {
 	e := &ExpectedClose{}
-	c.expectations = append(c.expectations, e)
+	c.expect(e)
 	return e
 }
 
@@ -156,96 +337,259 @@ func (c *pgxmock) MatchExpectationsInOrder(b bool) {
 	c.ordered = b
 }
 
+func (c *pgxmock) RequireAllQueriesInTx(b bool) {
+	c.requireTx = b
+}
+
 func (c *pgxmock) ExpectationsWereMet() error {
 	for _, e := range c.expectations {
-		e.Lock()
-		fulfilled := e.fulfilled() || !e.required()
-		e.Unlock()
+		if err := unmetError(e); err != nil {
+			if reason := e.rationale(); reason != "" {
+				return fmt.Errorf("%w\nexpected because: %s", err, reason)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// unmetError returns the reason the expectation is not met, if any
+func unmetError(e expectation) error {
+	e.Lock()
+	fulfilled := e.fulfilled() || !e.required()
+	failed := e.retriesFailed()
+	e.Unlock()
 
-		if !fulfilled {
-			return fmt.Errorf("there is a remaining expectation which was not matched: %s", e)
+	if failed {
+		return fmt.Errorf("expectation was retried without success: %s", e)
+	}
+	if !fulfilled {
+		if label := e.caption(); label != "" {
+			return fmt.Errorf("there is a remaining unmet expectation '%s': %s", label, e)
 		}
+		return fmt.Errorf("there is a remaining expectation which was not matched: %s", e)
+	}
 
-		// for expected prepared statement check whether it was closed if expected
-		if prep, ok := e.(*ExpectedPrepare); ok {
-			if prep.mustBeClosed && !prep.deallocated {
-				return fmt.Errorf("expected prepared statement to be closed, but it was not: %s", prep)
-			}
+	// for expected prepared statement check whether it was closed if expected
+	if prep, ok := e.(*ExpectedPrepare); ok {
+		if prep.mustBeClosed && !prep.deallocated {
+			return fmt.Errorf("expected prepared statement to be closed, but it was not: %s", prep)
 		}
+	}
 
-		// must check whether all expected queried rows are closed
-		if query, ok := e.(*ExpectedQuery); ok {
-			if query.rowsMustBeClosed && !query.rowsWereClosed {
-				return fmt.Errorf("expected query rows to be closed, but it was not: %s", query)
-			}
+	if closing, ok := e.(*ExpectedClose); ok {
+		closing.Lock()
+		repeated := closing.repeated
+		closing.Unlock()
+		if repeated > 0 {
+			return fmt.Errorf("expected pool to be closed exactly once, but it was closed %d times: %s", repeated+1, closing)
+		}
+	}
+
+	// must check whether all expected queried rows are closed
+	if query, ok := e.(*ExpectedQuery); ok {
+		if query.rowsMustBeClosed && !query.rowsWereClosed {
+			return fmt.Errorf("expected query rows to be closed, but it was not: %s", query)
 		}
 	}
 	return nil
 }
 
+// pollInterval is the interval between ExpectationsWereMetWithin checks
+const pollInterval = 5 * time.Millisecond
+
+func (c *pgxmock) ExpectationsWereMetWithin(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		err := c.ExpectationsWereMet()
+		if err == nil || !time.Now().Before(deadline) {
+			return err
+		}
+		time.Sleep(min(pollInterval, time.Until(deadline)))
+	}
+}
+
+func (c *pgxmock) Check(t TestingT) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+	if err := c.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+		return false
+	}
+	return true
+}
+
 func (c *pgxmock) ExpectQuery(expectedSQL string) *ExpectedQuery {
 	e := &ExpectedQuery{}
 	e.expectSQL = expectedSQL
-	c.expectations = append(c.expectations, e)
+	e.queryMatcher = c.queryMatcher
+	c.expect(e)
+	return e
+}
+
+func (c *pgxmock) ExpectQueryE(expectedSQL string) (*ExpectedQuery, error) {
+	if err := c.validateExpectedSQL(expectedSQL); err != nil {
+		return nil, err
+	}
+	return c.ExpectQuery(expectedSQL), nil
+}
+
+func (c *pgxmock) ExpectExecE(expectedSQL string) (*ExpectedExec, error) {
+	if err := c.validateExpectedSQL(expectedSQL); err != nil {
+		return nil, err
+	}
+	return c.ExpectExec(expectedSQL), nil
+}
+
+// validateExpectedSQL checks the expected SQL is a valid regular
+// expression if QueryMatcherRegexp is used
+func (c *pgxmock) validateExpectedSQL(expectedSQL string) error {
+	if c.queryMatcher != QueryMatcherRegexp {
+		return nil
+	}
+	if _, err := regexp.Compile(stripQuery(expectedSQL)); err != nil {
+		return fmt.Errorf("invalid expected sql regexp '%s': %w", expectedSQL, err)
+	}
+	return nil
+}
+
+func (c *pgxmock) ExpectQueryOnReplica(expectedSQL string) *ExpectedQuery {
+	e := c.ExpectQuery(expectedSQL)
+	e.role = RoleReplica
+	return e
+}
+
+func (c *pgxmock) ExpectAnyQuery() *ExpectedQuery {
+	e := &ExpectedQuery{}
+	e.catchAll, e.optional = true, true
+	e.expectSQL = "(any)"
+	e.WillReturnRows(NewRows(nil))
+	c.expect(e)
+	return e
+}
+
+func (c *pgxmock) ExpectAnyExec() *ExpectedExec {
+	e := &ExpectedExec{}
+	e.catchAll, e.optional = true, true
+	e.expectSQL = "(any)"
+	c.expect(e)
 	return e
 }
 
 func (c *pgxmock) ExpectCommit() *ExpectedCommit {
 	e := &ExpectedCommit{}
-	c.expectations = append(c.expectations, e)
+	c.expect(e)
 	return e
 }
 
 func (c *pgxmock) ExpectRollback() *ExpectedRollback {
 	e := &ExpectedRollback{}
-	c.expectations = append(c.expectations, e)
+	c.expect(e)
 	return e
 }
 
 func (c *pgxmock) ExpectBegin() *ExpectedBegin {
 	e := &ExpectedBegin{}
-	c.expectations = append(c.expectations, e)
+	c.expect(e)
 	return e
 }
 
 func (c *pgxmock) ExpectBeginTx(txOptions pgx.TxOptions) *ExpectedBegin {
 	e := &ExpectedBegin{opts: txOptions}
-	c.expectations = append(c.expectations, e)
+	c.expect(e)
 	return e
 }
 
 func (c *pgxmock) ExpectExec(expectedSQL string) *ExpectedExec {
 	e := &ExpectedExec{}
 	e.expectSQL = expectedSQL
-	c.expectations = append(c.expectations, e)
+	e.queryMatcher = c.queryMatcher
+	c.expect(e)
+	return e
+}
+
+func (c *pgxmock) ExpectSearchPath(schemas ...string) *ExpectedExec {
+	e := &ExpectedExec{}
+	quoted := make([]string, len(schemas))
+	for i, schema := range schemas {
+		quoted[i] = pgx.Identifier{schema}.Sanitize()
+	}
+	e.expectSQL = "SET search_path TO " + strings.Join(quoted, ", ")
+	e.queryMatcher = searchPathMatcher{}
+	e.WillReturnResult(pgconn.NewCommandTag("SET"))
+	c.expect(e)
+	return e
+}
+
+func (c *pgxmock) ExpectExecMulti(expectedSQL ...string) *ExpectedExec {
+	e := &ExpectedExec{}
+	e.expectSQL = strings.Join(expectedSQL, "; ")
+	e.queryMatcher = multiStatementMatcher{patterns: expectedSQL, matcher: c.queryMatcher}
+	c.expect(e)
 	return e
 }
 
 func (c *pgxmock) ExpectCopyFrom(expectedTableName pgx.Identifier, expectedColumns []string) *ExpectedCopyFrom {
 	e := &ExpectedCopyFrom{expectedTableName: expectedTableName, expectedColumns: expectedColumns}
-	c.expectations = append(c.expectations, e)
+	c.expect(e)
+	return e
+}
+
+func (c *pgxmock) ExpectSendBatch(expectedBatch *Batch) *ExpectedBatch {
+	for _, be := range expectedBatch.elements {
+		if be.queryMatcher == nil {
+			be.queryMatcher = c.queryMatcher
+		}
+	}
+	e := &ExpectedBatch{batch: expectedBatch}
+	c.expect(e)
+	return e
+}
+
+func (c *pgxmock) ExpectSendBatchLen(n int) *ExpectedBatch {
+	e := &ExpectedBatch{batch: NewBatch(), lenOnly: true, expectedLen: n}
+	c.expect(e)
+	return e
+}
+
+func (c *pgxmock) ExpectAcquire() *ExpectedAcquire {
+	e := &ExpectedAcquire{}
+	c.expect(e)
 	return e
 }
 
 // ExpectReset expects Reset to be called.
 func (c *pgxmock) ExpectReset() *ExpectedReset {
 	e := &ExpectedReset{}
-	c.expectations = append(c.expectations, e)
+	c.expect(e)
+	return e
+}
+
+func (c *pgxmock) ExpectCancelRequest() *ExpectedCancelRequest {
+	e := &ExpectedCancelRequest{}
+	c.expect(e)
 	return e
 }
 
 func (c *pgxmock) ExpectPing() *ExpectedPing {
 	e := &ExpectedPing{}
-	c.expectations = append(c.expectations, e)
+	c.expect(e)
 	return e
 }
 
 func (c *pgxmock) ExpectPrepare(expectedStmtName, expectedSQL string) *ExpectedPrepare {
-	e := &ExpectedPrepare{expectSQL: expectedSQL, expectStmtName: expectedStmtName, mock: c}
-	c.expectations = append(c.expectations, e)
+	e := &ExpectedPrepare{expectSQL: expectedSQL, expectStmtName: expectedStmtName}
+	c.expect(e)
 	return e
 }
 
+func (c *pgxmock) ExpectPrepareQuery(expectedStmtName, expectedSQL string) *ExpectedQuery {
+	return c.ExpectPrepare(expectedStmtName, expectedSQL).ExpectQuery().
+		WithQueryMatcher(QueryMatcherEqual).
+		MustUsePreparedStatement()
+}
+
 //endregion Expectations
 
 // NewRows allows Rows to be created from a
@@ -276,8 +620,42 @@ func (c *pgxmock) NewColumn(name string) *pgconn.FieldDescription {
 	return &pgconn.FieldDescription{Name: name}
 }
 
+// expect registers the expectation to be matched by calls of this mock
+func (c *pgxmock) expect(e expectation) {
+	e.attach(c)
+	c.expectations = append(c.expectations, e)
+}
+
+// clone copies the mock configuration resetting its state
+func (c *pgxmock) clone() pgxmock {
+	clone := *c
+	clone.expectations = nil
+	clone.prepared = &sync.Map{}
+	clone.closed = &atomic.Bool{}
+	clone.openTx = &atomic.Int32{}
+	clone.rowsCopied = &atomic.Int64{}
+	clone.acquired = &atomic.Int32{}
+	clone.typeMap = c.newTypeMap()
+	return clone
+}
+
+// newTypeMap returns the type map set up by TypeMapOption if any,
+// every mock has its own map since pgtype.Map is not concurrency safe
+func (c *pgxmock) newTypeMap() *pgtype.Map {
+	m := pgtype.NewMap()
+	if c.typeMapSetup != nil {
+		c.typeMapSetup(m)
+	}
+	return m
+}
+
 // open a mock database driver connection
 func (c *pgxmock) open(options []func(*pgxmock) error) error {
+	c.prepared = &sync.Map{}
+	c.closed = &atomic.Bool{}
+	c.openTx = &atomic.Int32{}
+	c.rowsCopied = &atomic.Int64{}
+	c.acquired = &atomic.Int32{}
 	for _, option := range options {
 		err := option(c)
 		if err != nil {
@@ -288,6 +666,10 @@ func (c *pgxmock) open(options []func(*pgxmock) error) error {
 	if c.queryMatcher == nil {
 		c.queryMatcher = QueryMatcherRegexp
 	}
+	c.typeMap = c.newTypeMap()
+	if c.warningHandler == nil {
+		c.warningHandler = func(string) {}
+	}
 
 	return nil
 }
@@ -295,20 +677,30 @@ func (c *pgxmock) open(options []func(*pgxmock) error) error {
 // Close a mock database driver connection. It may or may not
 // be called depending on the circumstances, but if it is called
 // there must be an *ExpectedClose expectation satisfied.
+// It is safe to call Close on an already closed connection.
 func (c *pgxmock) Close(ctx context.Context) error {
+	if c.IsClosed() {
+		return nil
+	}
 	ex, err := findExpectation[*ExpectedClose](c, "Close()")
 	if err != nil {
 		return err
 	}
+	c.closed.Store(true)
 	return ex.waitForDelay(ctx)
 }
 
+// IsClosed reports if the connection has been closed
+func (c *pgxmock) IsClosed() bool {
+	return c.closed.Load()
+}
+
 func (c *pgxmock) Conn() *pgx.Conn {
 	panic("Conn() is not available in pgxmock")
 }
 
-func (c *pgxmock) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, _ pgx.CopyFromSource) (int64, error) {
-	ex, err := findExpectationFunc[*ExpectedCopyFrom](c, "BeginTx()", func(copyExp *ExpectedCopyFrom) error {
+func (c *pgxmock) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	ex, err := findExpectationFunc[*ExpectedCopyFrom](c, "CopyFrom()", func(copyExp *ExpectedCopyFrom) error {
 		if !reflect.DeepEqual(copyExp.expectedTableName, tableName) {
 			return fmt.Errorf("CopyFrom: table name '%s' was not expected, expected table name is '%s'", tableName, copyExp.expectedTableName)
 		}
@@ -320,11 +712,37 @@ func (c *pgxmock) CopyFrom(ctx context.Context, tableName pgx.Identifier, column
 	if err != nil {
 		return -1, err
 	}
-	return ex.rowsAffected, ex.waitForDelay(ctx)
+	if ex.failErr == nil {
+		n := ex.CommandTag().RowsAffected()
+		if err = ex.waitForDelay(ctx); err == nil {
+			c.rowsCopied.Add(n)
+		}
+		return n, err
+	}
+	if err = ex.waitForDelay(ctx); err != nil {
+		return 0, err
+	}
+	return ex.copyRows(rowSrc)
 }
 
-func (c *pgxmock) SendBatch(context.Context, *pgx.Batch) pgx.BatchResults {
-	return nil
+func (c *pgxmock) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	br := &batchResults{batch: b}
+	if b != nil {
+		for _, msg := range aliasedArgs(b.QueuedQueries) {
+			c.warningHandler(msg)
+		}
+	}
+	ex, err := findExpectationFunc[*ExpectedBatch](c, "SendBatch()", func(batchExp *ExpectedBatch) (err error) {
+		br.elements, err = batchExp.batchMatches(c, b)
+		return err
+	})
+	if err != nil {
+		br.err = err
+		return br
+	}
+	br.ex = ex
+	br.err = ex.waitForDelay(ctx)
+	return br
 }
 
 func (c *pgxmock) LargeObjects() pgx.LargeObjects {
@@ -348,6 +766,7 @@ func (c *pgxmock) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx,
 	if err = ex.waitForDelay(ctx); err != nil {
 		return nil, err
 	}
+	c.openTx.Add(1)
 	return c, nil
 }
 
@@ -367,14 +786,44 @@ func (c *pgxmock) Prepare(ctx context.Context, name, query string) (*pgconn.Stat
 	if err = ex.waitForDelay(ctx); err != nil {
 		return nil, err
 	}
+	c.prepared.Store(name, query)
 	return &pgconn.StatementDescription{Name: name, SQL: query}, nil
 }
 
+// preparedSQL returns SQL of the statement prepared with the given name
+func (c *pgxmock) preparedSQL(name string) (string, bool) {
+	sql, ok := c.prepared.Load(name)
+	if !ok {
+		return "", false
+	}
+	return sql.(string), true
+}
+
+// queryMatches checks whether the expectation matches the actual SQL
+// and arguments. If a prepared statement name is used instead of SQL,
+// the expectation may be written against either the name or the SQL.
+// Expectations set with MustUsePreparedStatement never match inline SQL.
+func (c *pgxmock) queryMatches(e *queryBasedExpectation, sql string, args []interface{}) error {
+	preparedSQL, prepared := c.preparedSQL(sql)
+	err := e.queryMatches(sql, args)
+	if err != nil && prepared && e.queryMatches(preparedSQL, args) == nil {
+		err = nil
+	}
+	if err == nil && e.mustUsePrepared && !prepared {
+		e.captures = nil
+		return fmt.Errorf("inline SQL '%s' passed, but prepared statement expected: %s", sql, e.expectSQL)
+	}
+	return err
+}
+
 func (c *pgxmock) Deallocate(ctx context.Context, name string) error {
 	var (
 		expected *ExpectedPrepare
 		ok       bool
 	)
+	if c.IsClosed() {
+		return fmt.Errorf("Deallocate: %w", errConnClosed)
+	}
 	for _, next := range c.expectations {
 		next.Lock()
 		expected, ok = next.(*ExpectedPrepare)
@@ -388,7 +837,21 @@ func (c *pgxmock) Deallocate(ctx context.Context, name string) error {
 		return fmt.Errorf("Deallocate: prepared statement name '%s' doesn't exist", name)
 	}
 	expected.deallocated = true
-	return expected.waitForDelay(ctx)
+	c.prepared.Delete(name)
+	// prepare errors set by WillReturnError are returned by Prepare() only
+	err := ctx.Err()
+	if err == nil {
+		select {
+		case <-time.After(expected.plannedDelay):
+			err = expected.deallocateErr
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+	if expected.panicArgument != nil {
+		panic(expected.panicArgument)
+	}
+	return err
 }
 
 func (c *pgxmock) Commit(ctx context.Context) error {
@@ -396,6 +859,7 @@ func (c *pgxmock) Commit(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	c.finishTx()
 	return ex.waitForDelay(ctx)
 }
 
@@ -404,31 +868,118 @@ func (c *pgxmock) Rollback(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	c.finishTx()
 	return ex.waitForDelay(ctx)
 }
 
+// finishTx marks the innermost open transaction as finished, the same way
+// as pgx does the transaction is finished even if Commit() or Rollback() fails
+func (c *pgxmock) finishTx() {
+	for {
+		n := c.openTx.Load()
+		if n == 0 || c.openTx.CompareAndSwap(n, n-1) {
+			return
+		}
+	}
+}
+
+func (c *pgxmock) AssertNoOpenTransactions() error {
+	if n := c.openTx.Load(); n > 0 {
+		return fmt.Errorf("there are %d transactions left open, expected Commit() or Rollback() to be called", n)
+	}
+	return nil
+}
+
+func (c *pgxmock) TypeMap() *pgtype.Map {
+	return c.typeMap
+}
+
+func (c *pgxmock) TotalRowsCopied() int64 {
+	return c.rowsCopied.Load()
+}
+
 // Implement the "QueryerContext" interface
+// queryContext applies the default query timeout if the context has no deadline
+func (c *pgxmock) queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || c.queryTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, c.queryTimeout)
+}
+
 func (c *pgxmock) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	ex, err := c.query(ctx, sql, args, false)
+	if err != nil {
+		return nil, err
+	}
+	return c.queryResult(ctx, ex, sql, args)
+}
+
+// queryResult returns rows of the matched expectation after the planned delay
+func (c *pgxmock) queryResult(ctx context.Context, ex *ExpectedQuery, sql string, args []interface{}) (pgx.Rows, error) {
+	qctx, cancel := c.queryContext(ctx)
+	defer cancel()
+	rows := ex.rows
+	if ex.rowsFn != nil {
+		rows = &rowSets{sets: []*Rows{ex.rowsFn(args)}, ex: ex}
+	}
+	if rs, ok := rows.(*rowSets); ok && len(rs.sets) == 1 && rs.sets[0].fetchSize > 0 {
+		ex.Lock()
+		fetched := int(ex.triggered)
+		ex.Unlock()
+		rows = &rowSets{sets: []*Rows{rs.sets[0].fetch(fetched - 1)}, ex: ex}
+	}
+	if ex.groupsFn != nil {
+		groups, ok := ex.captureGroups(sql)
+		if preparedSQL, prepared := c.preparedSQL(sql); !ok && prepared {
+			groups, _ = ex.captureGroups(preparedSQL)
+		}
+		rows = &rowSets{sets: []*Rows{ex.groupsFn(groups)}, ex: ex}
+	}
+	if rs, ok := rows.(*rowSets); ok {
+		rs.ctx, rs.err = ctx, nil // rows are read after Query() returns, so the caller context is used
+	}
+	return rows, ex.waitForDelay(qctx)
+}
+
+// query finds the expectation matching Query() or QueryRow() call,
+// custom pgx.Row of the expectation may be returned by QueryRow() only
+func (c *pgxmock) query(ctx context.Context, sql string, args []interface{}, singleRow bool) (*ExpectedQuery, error) {
+	if err := c.validateCall(sql, args); err != nil {
+		return nil, err
+	}
 	ex, err := findExpectationFunc[*ExpectedQuery](c, "Query()", func(queryExp *ExpectedQuery) error {
-		if err := c.queryMatcher.Match(queryExp.expectSQL, sql); err != nil {
+		if queryExp.catchAll {
+			return nil
+		}
+		if err := c.queryMatches(&queryExp.queryBasedExpectation, sql, args); err != nil {
 			return err
 		}
-		if rewrittenSQL, err := queryExp.argsMatches(sql, args); err != nil {
+		if err := c.operationMatches(ctx, &queryExp.queryBasedExpectation); err != nil {
 			return err
-		} else if rewrittenSQL != "" && queryExp.expectRewrittenSQL != "" {
-			if err := c.queryMatcher.Match(queryExp.expectRewrittenSQL, rewrittenSQL); err != nil {
-				return err
-			}
 		}
-		if queryExp.err == nil && queryExp.rows == nil {
+		if err := roleMatches(ctx, &queryExp.queryBasedExpectation); err != nil {
+			return err
+		}
+		if err := contextMatches(ctx, &queryExp.queryBasedExpectation); err != nil {
+			return err
+		}
+		if n := queryExp.cursorFetches(); n > 0 && queryExp.plannedCalls > 0 {
+			return fmt.Errorf("Query: Times(%d) can't be used for rows returned by AsCursor() in %d fetches: %s", queryExp.plannedCalls, n, queryExp)
+		}
+		if queryExp.row != nil && !singleRow {
+			return fmt.Errorf("Query: custom row may be returned only by QueryRow(): %v", queryExp)
+		}
+		if queryExp.err == nil && queryExp.rows == nil && queryExp.row == nil &&
+			queryExp.rowsFn == nil && queryExp.groupsFn == nil {
 			return fmt.Errorf("Query must return a result rows or raise an error: %v", queryExp)
 		}
 		return nil
 	})
 	if err != nil {
-		return nil, err
+		c.panicUnexpected(fmt.Sprintf("Query(%q, %v)", sql, args), err)
 	}
-	return ex.rows, ex.waitForDelay(ctx)
+	return ex, err
 }
 
 type errRow struct {
@@ -440,25 +991,47 @@ func (er errRow) Scan(...interface{}) error {
 }
 
 func (c *pgxmock) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
-	rows, err := c.Query(ctx, sql, args...)
+	ex, err := c.query(ctx, sql, args, true)
+	if err != nil {
+		return errRow{err}
+	}
+	rows, err := c.queryResult(ctx, ex, sql, args)
 	if err != nil {
 		return errRow{err}
 	}
+	if ex.row != nil {
+		return ex.row
+	}
+	if rs, ok := rows.(*rowSets); ok && ex.singleRow {
+		if n := len(rs.sets[rs.RowSetNo].rows); n > 1 {
+			return errRow{fmt.Errorf("QueryRow: single row expected, but query returned %d rows: %s", n, sql)}
+		}
+	}
 	_ = rows.Next()
 	return rows
 }
 
 func (c *pgxmock) Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	ctx, cancel := c.queryContext(ctx)
+	defer cancel()
+	if err := c.validateCall(query, args); err != nil {
+		return pgconn.NewCommandTag(""), err
+	}
 	ex, err := findExpectationFunc[*ExpectedExec](c, "Exec()", func(execExp *ExpectedExec) error {
-		if err := c.queryMatcher.Match(execExp.expectSQL, query); err != nil {
+		if execExp.catchAll {
+			return nil
+		}
+		if err := c.queryMatches(&execExp.queryBasedExpectation, query, args); err != nil {
 			return err
 		}
-		if rewrittenSQL, err := execExp.argsMatches(query, args); err != nil {
+		if err := execExp.statementsMatch(query); err != nil {
+			return err
+		}
+		if err := c.operationMatches(ctx, &execExp.queryBasedExpectation); err != nil {
+			return err
+		}
+		if err := contextMatches(ctx, &execExp.queryBasedExpectation); err != nil {
 			return err
-		} else if rewrittenSQL != "" && execExp.expectRewrittenSQL != "" {
-			if err := c.queryMatcher.Match(execExp.expectRewrittenSQL, rewrittenSQL); err != nil {
-				return err
-			}
 		}
 		if execExp.result.String() == "" && execExp.err == nil {
 			return fmt.Errorf("Exec must return a result or raise an error: %s", execExp)
@@ -466,13 +1039,34 @@ func (c *pgxmock) Exec(ctx context.Context, query string, args ...interface{}) (
 		return nil
 	})
 	if err != nil {
+		c.panicUnexpected(fmt.Sprintf("Exec(%q, %v)", query, args), err)
 		return pgconn.NewCommandTag(""), err
 	}
+	ex.Lock()
+	ex.executed += max(len(splitStatements(query)), 1)
+	ex.Unlock()
 	return ex.result, ex.waitForDelay(ctx)
 }
 
 func (c *pgxmock) Ping(ctx context.Context) (err error) {
 	ex, err := findExpectation[*ExpectedPing](c, "Ping()")
+	if err != nil {
+		c.panicUnexpected("Ping()", err)
+		return err
+	}
+	return ex.waitForDelay(ctx)
+}
+
+// panicUnexpected panics if PanicOnUnexpected is set and the call
+// does not match any expectation, calls to a closed mock are not affected
+func (c *pgxmock) panicUnexpected(call string, err error) {
+	if c.panicOnUnexpected && !errors.Is(err, errConnClosed) {
+		panic(fmt.Sprintf("pgxmock: unexpected call %s: %s\n%s", call, err, debug.Stack()))
+	}
+}
+
+func (c *pgxmock) CancelRequest(ctx context.Context) error {
+	ex, err := findExpectation[*ExpectedCancelRequest](c, "CancelRequest()")
 	if err != nil {
 		return err
 	}
@@ -497,7 +1091,20 @@ func findExpectationFunc[ET expectationType[t], t any](c *pgxmock, method string
 	var fulfilled int
 	var ok bool
 	var err error
-	for _, next := range c.expectations {
+	var skipped []expectation // optional expectations passed over in order mode
+	if c.IsClosed() {
+		return nil, fmt.Errorf("call to method %s failed: %w", method, errConnClosed)
+	}
+	ordered := c.ordered
+	if _, batch := any(expected).(*ExpectedBatch); batch && c.unorderedBatches {
+		ordered = false // batches are matched by content, see UnorderedBatches
+	}
+	candidates := c.expectations
+	if !ordered {
+		candidates = c.prioritized()
+	}
+	var blocking expectation // required expectation not matching the call in order mode
+	for _, next := range candidates {
 		next.Lock()
 		if next.fulfilled() {
 			next.Unlock()
@@ -510,34 +1117,125 @@ func findExpectationFunc[ET expectationType[t], t any](c *pgxmock, method string
 			if err == nil {
 				break
 			}
+			expected = nil
 		}
-		if c.ordered {
+		if ordered {
 			if (!ok || err != nil) && !next.required() {
 				next.Unlock()
+				if ok {
+					skipped = append(skipped, next)
+				}
 				continue
 			}
 			next.Unlock()
-			if err != nil {
-				return nil, err
-			}
-			return nil, fmt.Errorf("call to method %s, was not expected, next expectation is: %s", method, next)
+			blocking = next
+			break
 		}
 		next.Unlock()
 	}
-
-	if expected == nil {
-		msg := fmt.Sprintf("call to method %s was not expected", method)
-		if fulfilled == len(c.expectations) {
-			msg = "all expectations were already fulfilled, " + msg
+	if ordered {
+		if prioritized := findPrioritized(c, cmp, expected, blocking); prioritized != nil {
+			if expected != nil {
+				expected.Unlock()
+			}
+			expected, blocking = prioritized, nil
 		}
-		return nil, fmt.Errorf(msg)
+	}
+	if blocking != nil {
+		return blockedCall[ET](c, method, blocking, err)
+	}
+	if expected == nil {
+		return nil, unexpectedCall(method, fulfilled == len(c.expectations), err)
 	}
 	defer expected.Unlock()
 
+	for _, e := range skipped {
+		e.Lock()
+		e.skip()
+		e.Unlock()
+	}
 	expected.fulfill()
 	return expected, nil
 }
 
+// prioritized returns expectations sorted by priority in descending order,
+// expectations with the same priority keep the order they were set
+func (c *pgxmock) prioritized() []expectation {
+	expectations := slices.Clone(c.expectations)
+	slices.SortStableFunc(expectations, func(a, b expectation) int {
+		return cmp.Compare(b.weight(), a.weight())
+	})
+	return expectations
+}
+
+// blockedCall returns the catch-all expectation of the type if any, otherwise
+// the error of the call not matching the next expectation in order mode
+func blockedCall[ET expectationType[t], t any](c *pgxmock, method string, blocking expectation, err error) (ET, error) {
+	if fallback := findFallback[ET](c); fallback != nil {
+		return fallback, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("call to method %s, was not expected, next expectation is: %s", method, blocking)
+}
+
+// unexpectedCall returns the error of the call not matching any expectation,
+// err is the mismatch of the last expectation of the type compared, if any
+func unexpectedCall(method string, allFulfilled bool, err error) error {
+	msg := fmt.Sprintf("call to method %s was not expected", method)
+	if allFulfilled {
+		msg = "all expectations were already fulfilled, " + msg
+	}
+	if err != nil {
+		// the last expectation of the type compared shows the closest mismatch
+		return fmt.Errorf("%s, closest mismatch: %w", msg, err)
+	}
+	return errors.New(msg)
+}
+
+// findPrioritized returns the locked expectation of the type matching the call
+// with higher priority than the one matched or blocking the call in order mode
+func findPrioritized[ET expectationType[t], t any](c *pgxmock, cmp func(ET) error, matched ET, blocking expectation) ET {
+	var threshold int
+	switch {
+	case matched != nil:
+		threshold = matched.weight()
+	case blocking != nil:
+		threshold = blocking.weight()
+	default:
+		return nil // every expectation was compared already
+	}
+	for _, next := range c.prioritized() {
+		if next.weight() <= threshold {
+			break
+		}
+		candidate, ok := next.(ET)
+		if !ok || any(candidate) == any(matched) {
+			continue
+		}
+		candidate.Lock()
+		if !candidate.fulfilled() && cmp(candidate) == nil {
+			return candidate
+		}
+		candidate.Unlock()
+	}
+	return nil
+}
+
+// findFallback returns the catch-all expectation of the type if any
+func findFallback[ET expectationType[t], t any](c *pgxmock) ET {
+	for _, next := range c.expectations {
+		if fallback, ok := next.(ET); ok && fallback.fallback() {
+			fallback.Lock()
+			defer fallback.Unlock()
+			fallback.fulfill()
+			return fallback
+		}
+	}
+	return nil
+}
+
 func findExpectation[ET expectationType[t], t any](c *pgxmock, method string) (ET, error) {
 	return findExpectationFunc[ET, t](c, method, func(_ ET) error { return nil })
 }
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	pgx "github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/stretchr/testify/assert"
 )
@@ -969,6 +970,68 @@ func TestPrepareQuery(t *testing.T) {
 	}
 }
 
+func TestExpectPrepareQuery(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	mock, _ := NewConn()
+	mock.ExpectPrepareQuery("order_by_id", `SELECT id, status FROM orders WHERE id = \$1`).
+		WithArgs(101).
+		WillReturnRows(NewRows([]string{"id", "status"}).AddRow(101, "paid"))
+
+	_, err := mock.Query(ctx, "order_by_id", 101)
+	a.Error(err, "statement must be prepared first")
+	_, err = mock.Prepare(ctx, "order_by_id", "SELECT id, status FROM orders WHERE id = $1")
+	a.NoError(err)
+	var status string
+	err = mock.QueryRow(ctx, "SELECT id, status FROM orders WHERE id = $1", 101).Scan(new(int), &status)
+	a.Error(err, "inline SQL must not match")
+	a.NoError(mock.QueryRow(ctx, "order_by_id", 101).Scan(new(int), &status))
+	a.Equal("paid", status)
+	a.NoError(mock.ExpectationsWereMet())
+
+	mock, _ = NewConn()
+	mock.MatchExpectationsInOrder(false)
+	mock.ExpectPrepareQuery("order_by_id", `SELECT id, status FROM orders WHERE id = \$1`).
+		WithArgs(101).
+		WillReturnRows(NewRows([]string{"id", "status"}).AddRow(101, "paid"))
+	_, err = mock.Query(ctx, "order_by_id", 101)
+	a.Error(err, "statement must be prepared first in unordered mode too")
+	_, err = mock.Prepare(ctx, "order_by_id", "SELECT id, status FROM orders WHERE id = $1")
+	a.NoError(err)
+	a.NoError(mock.QueryRow(ctx, "order_by_id", 101).Scan(new(int), &status))
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestPreparedStatementNameResolution(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+
+	sql := "SELECT name FROM users WHERE id = $1"
+	mock.ExpectPrepare("getUser", "SELECT name FROM users")
+	mock.ExpectQuery("SELECT name FROM users WHERE id").
+		WithArgs(42).
+		WillReturnRows(NewRows([]string{"name"}).AddRow("John"))
+	mock.ExpectExec("getUser").
+		WithArgs(42).
+		WillReturnResult(NewResult("SELECT", 1))
+	mock.ExpectExec("SELECT name FROM users WHERE id").
+		WithArgs(42).
+		WillReturnResult(NewResult("SELECT", 1))
+
+	_, err := mock.Prepare(ctx, "getUser", sql)
+	a.NoError(err)
+	var name string
+	a.NoError(mock.QueryRow(ctx, "getUser", 42).Scan(&name), "statement name should be resolved to SQL")
+	a.Equal("John", name)
+	_, err = mock.Exec(ctx, "getUser", 42)
+	a.NoError(err, "statement name should be matched as well")
+
+	a.NoError(mock.Deallocate(ctx, "getUser"))
+	_, err = mock.Exec(ctx, "getUser", 42)
+	a.Error(err, "deallocated statement name should not be resolved")
+}
+
 func TestExpectedCloseError(t *testing.T) {
 	// Open new mock database
 	mock, err := NewConn()
@@ -1001,6 +1064,30 @@ func TestExpectedCloseOrder(t *testing.T) {
 	}
 }
 
+func TestIsClosed(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+	ctx := context.Background()
+	a.False(mock.IsClosed())
+
+	mock.ExpectClose()
+	a.NoError(mock.Close(ctx))
+	a.True(mock.IsClosed())
+	a.NoError(mock.Close(ctx), "closing already closed connection is safe")
+
+	_, err := mock.Exec(ctx, "DELETE FROM foo")
+	a.ErrorIs(err, errConnClosed)
+	_, err = mock.Query(ctx, "SELECT 1")
+	a.ErrorIs(err, errConnClosed)
+	a.ErrorIs(mock.Ping(ctx), errConnClosed)
+	a.ErrorIs(mock.Deallocate(ctx, "foo"), errConnClosed)
+	a.NoError(mock.ExpectationsWereMet())
+
+	clone, _ := mock.Clone()
+	a.False(clone.IsClosed(), "clone must start opened")
+}
+
 func TestExpectedBeginOrder(t *testing.T) {
 	// Open new mock database
 	mock, err := NewConn()
@@ -1166,6 +1253,38 @@ func queryWithTimeout(t time.Duration, db PgxCommonIface, query string, args ...
 	}
 }
 
+func TestDefaultQueryTimeout(t *testing.T) {
+	t.Parallel()
+	mock, err := NewPool(WithDefaultQueryTimeout(10 * time.Millisecond))
+	a := assert.New(t)
+	a.NoError(err)
+
+	mock.ExpectQuery("SELECT pg_sleep").
+		WillReturnRows(NewRows([]string{"pg_sleep"})).
+		WillDelayFor(time.Second)
+	mock.ExpectExec("UPDATE").
+		WillReturnResult(NewResult("UPDATE", 1)).
+		WillDelayFor(time.Second)
+	mock.ExpectExec("UPDATE").
+		WillReturnResult(NewResult("UPDATE", 1)).
+		WillDelayFor(20 * time.Millisecond)
+	mock.ExpectExec("DELETE").
+		WillReturnResult(NewResult("DELETE", 1))
+
+	_, err = mock.Query(ctx, "SELECT pg_sleep(1)")
+	a.ErrorIs(err, context.DeadlineExceeded)
+	_, err = mock.Exec(ctx, "UPDATE foo SET bar = 1")
+	a.ErrorIs(err, context.DeadlineExceeded)
+
+	c, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	_, err = mock.Exec(c, "UPDATE foo SET bar = 1")
+	a.NoError(err, "context deadline must take precedence over default timeout")
+	_, err = mock.Exec(ctx, "DELETE FROM foo")
+	a.NoError(err)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
 func TestUnmockedMethods(t *testing.T) {
 	mock, _ := NewPool()
 	a := assert.New(t)
@@ -1173,7 +1292,7 @@ func TestUnmockedMethods(t *testing.T) {
 	a.NotNil(mock.AsConn().Config())
 	a.NotNil(mock.AcquireAllIdle(ctx))
 	a.Nil(mock.AcquireFunc(ctx, func(*pgxpool.Conn) error { return nil }))
-	a.Nil(mock.SendBatch(ctx, nil))
+	a.Error(mock.SendBatch(ctx, nil).Close(), "SendBatch() was not expected")
 	a.Zero(mock.LargeObjects())
 	a.Panics(func() { _ = mock.Conn() })
 }
@@ -1198,3 +1317,295 @@ func TestExpectReset(t *testing.T) {
 	mock.ExpectReset()
 	a.Error(mock.ExpectationsWereMet())
 }
+
+type panicRow struct{}
+
+func (panicRow) Scan(...any) error {
+	panic("cannot decode value")
+}
+
+func TestWillReturnRow(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+	ctx := context.Background()
+
+	mock.ExpectQuery("SELECT name").WillReturnRow(panicRow{})
+	row := mock.QueryRow(ctx, "SELECT name FROM users")
+	a.IsType(panicRow{}, row)
+	a.Panics(func() { _ = row.Scan() })
+
+	mock.ExpectQuery("SELECT name").WillReturnRow(panicRow{}).WillReturnError(errPanic)
+	_, err := mock.Query(ctx, "SELECT name FROM users")
+	a.Error(err, "custom row must not be returned by Query()")
+	a.Error(mock.ExpectationsWereMet(), "expectation must remain unmet")
+	a.ErrorIs(mock.QueryRow(ctx, "SELECT name FROM users").Scan(), errPanic)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestAssertNoOpenTransactions(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+	ctx := context.Background()
+	a.NoError(mock.AssertNoOpenTransactions())
+
+	mock.ExpectBegin()
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+	mock.ExpectRollback().WillReturnError(errors.New("rollback failed"))
+	tx, err := mock.Begin(ctx)
+	a.NoError(err)
+	nested, err := tx.Begin(ctx)
+	a.NoError(err)
+	a.NoError(nested.Commit(ctx))
+	a.ErrorContains(mock.AssertNoOpenTransactions(), "there are 1 transactions left open")
+	a.Error(tx.Rollback(ctx))
+	a.NoError(mock.AssertNoOpenTransactions(), "failed rollback finishes the transaction")
+
+	mock.ExpectBegin().WillReturnError(errors.New("begin failed"))
+	_, err = mock.Begin(ctx)
+	a.Error(err)
+	a.NoError(mock.AssertNoOpenTransactions(), "failed begin starts no transaction")
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestExpectAnyExecAndQuery(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+	ctx := context.Background()
+
+	mock.ExpectAnyExec()
+	mock.ExpectAnyQuery()
+	a.NoError(mock.ExpectationsWereMet(), "catch-all expectations are optional")
+	mock.ExpectExec("UPDATE orders").WithArgs(42).WillReturnResult(NewResult("UPDATE", 1))
+
+	res, err := mock.Exec(ctx, "INSERT INTO audit VALUES ($1)", "foo")
+	a.NoError(err, "unmatched Exec() must be caught even in order mode")
+	a.Zero(res.RowsAffected())
+	rows, err := mock.Query(ctx, "SELECT * FROM settings")
+	a.NoError(err)
+	a.False(rows.Next(), "empty rows must be returned")
+	rows.Close()
+	a.ErrorIs(mock.QueryRow(ctx, "SELECT 1").Scan(), pgx.ErrNoRows)
+	a.Error(mock.ExpectationsWereMet())
+
+	res, err = mock.Exec(ctx, "UPDATE orders", 42)
+	a.NoError(err)
+	a.EqualValues(1, res.RowsAffected(), "catch-all must have the lowest priority")
+	_, err = mock.Exec(ctx, "DELETE FROM audit")
+	a.NoError(err)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestPrepareError(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+	ctx := context.Background()
+	syntaxErr := &pgconn.PgError{Code: "42601", Message: "syntax error at or near \"SELEC\""}
+
+	mock.ExpectPrepare("bad", "SELEC 1").WillReturnError(syntaxErr)
+	stmt, err := mock.Prepare(ctx, "bad", "SELEC 1")
+	a.Nil(stmt)
+	var pgErr *pgconn.PgError
+	a.ErrorAs(err, &pgErr)
+	a.Equal("42601", pgErr.Code)
+	_, ok := mock.(*pgxmockConn).preparedSQL("bad")
+	a.False(ok, "failed statement must not be prepared")
+
+	errClose := errors.New("deallocate failed")
+	mock.ExpectPrepare("good", "SELECT 1").WillReturnCloseError(errClose)
+	_, err = mock.Prepare(ctx, "good", "SELECT 1")
+	a.NoError(err)
+	a.ErrorIs(mock.Deallocate(ctx, "good"), errClose)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestDeallocatePanic(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+	defer func() {
+		a.Equal("deallocate panic", recover())
+		a.NoError(mock.ExpectationsWereMet())
+	}()
+
+	ex := mock.ExpectPrepare("foo", "SELECT 1").WillBeDeallocated()
+	_, err := mock.Prepare(ctx, "foo", "SELECT 1")
+	a.NoError(err)
+	ex.WillPanic("deallocate panic")
+	_ = mock.Deallocate(ctx, "foo")
+	a.Fail("Deallocate() must panic")
+}
+
+func TestPanicOnUnexpected(t *testing.T) {
+	t.Parallel()
+	mock, err := NewConn(PanicOnUnexpected(true))
+	a := assert.New(t)
+	a.NoError(err)
+	ctx := context.Background()
+
+	recovered := func(f func()) (msg string) {
+		defer func() { msg, _ = recover().(string) }()
+		f()
+		return
+	}
+	msg := recovered(func() { _, _ = mock.Exec(ctx, "DELETE FROM users WHERE id = $1", 42) })
+	a.Contains(msg, `unexpected call Exec("DELETE FROM users WHERE id = $1", [42])`)
+	a.Contains(msg, "TestPanicOnUnexpected", "stack trace expected")
+	a.Contains(recovered(func() { _ = mock.QueryRow(ctx, "SELECT 1").Scan() }), `Query("SELECT 1", [])`)
+	a.Contains(recovered(func() { _ = mock.Ping(ctx) }), "unexpected call Ping()")
+
+	mock.ExpectPing()
+	mock.ExpectExec("DELETE").WillReturnResult(NewResult("DELETE", 1))
+	a.NotPanics(func() { a.NoError(mock.Ping(ctx)) })
+	a.NotPanics(func() {
+		_, err = mock.Exec(ctx, "DELETE FROM users")
+		a.NoError(err)
+	})
+	a.NoError(mock.ExpectationsWereMet())
+
+	mock.ExpectClose()
+	a.NoError(mock.Close(ctx))
+	a.NotPanics(func() { a.Error(mock.Ping(ctx)) }, "closed mock returns an error")
+}
+
+func TestExpectSearchPath(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+	ctx := context.Background()
+	mock.ExpectSearchPath("tenant_x", "public")
+	mock.ExpectSearchPath("Tenant_Y")
+	mock.ExpectSearchPath("tenant_z")
+
+	tag, err := mock.Exec(ctx, `SET search_path TO tenant_x, "public"`)
+	a.NoError(err)
+	a.Equal("SET", tag.String())
+	_, err = mock.Exec(ctx, `SET search_path = Tenant_Y`)
+	a.ErrorContains(err, `sets search_path to [tenant_y], but [Tenant_Y] expected`)
+	_, err = mock.Exec(ctx, `set local search_path = "Tenant_Y";`)
+	a.NoError(err)
+	_, err = mock.Exec(ctx, `SELECT set_config('search_path', 'tenant_z', false)`)
+	a.ErrorContains(err, "does not set search_path")
+	_, err = mock.Exec(ctx, `SET SESSION search_path TO 'tenant_z'`)
+	a.NoError(err)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestMustUsePreparedStatement(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+	ctx := context.Background()
+	mock.ExpectPrepare("get_user", "SELECT name FROM users WHERE id = \\$1")
+	mock.ExpectQuery("SELECT name FROM users").
+		WithArgs(42).
+		MustUsePreparedStatement().
+		WillReturnRows(NewRows([]string{"name"}).AddRow("john"))
+	mock.ExpectExec("UPDATE users").MustUsePreparedStatement().WillReturnResult(NewResult("UPDATE", 1))
+
+	_, err := mock.Prepare(ctx, "get_user", "SELECT name FROM users WHERE id = $1")
+	a.NoError(err)
+	_, err = mock.Query(ctx, "SELECT name FROM users WHERE id = $1", 42)
+	a.ErrorContains(err, "prepared statement expected")
+	var name string
+	a.NoError(mock.QueryRow(ctx, "get_user", 42).Scan(&name))
+	a.Equal("john", name)
+	_, err = mock.Exec(ctx, "UPDATE users SET name = 'john'")
+	a.ErrorContains(err, "inline SQL 'UPDATE users SET name = 'john'' passed")
+	a.Error(mock.ExpectationsWereMet())
+}
+
+func TestRollbackWithDoneContext(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	mock, _ := NewConn()
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE").WillReturnResult(NewResult("UPDATE", 1))
+	rollback := mock.ExpectRollback()
+
+	c, cancel := context.WithCancel(ctx)
+	tx, err := mock.Begin(c)
+	a.NoError(err)
+	_, err = tx.Exec(c, "UPDATE t SET id = 1")
+	a.NoError(err)
+	cancel() // e.g. the request is cancelled before the deferred cleanup
+	a.ErrorIs(tx.Rollback(c), context.Canceled)
+	a.Equal(1, rollback.MatchCount(), "rollback must be attempted")
+	a.NoError(mock.AssertNoOpenTransactions())
+	a.NoError(mock.ExpectationsWereMet())
+
+	mock.ExpectBegin()
+	mock.ExpectRollback().WillReturnError(errors.New("rollback failed"))
+	tx, err = mock.Begin(ctx)
+	a.NoError(err)
+	expired, cancel := context.WithDeadline(ctx, time.Now().Add(-time.Second))
+	defer cancel()
+	a.ErrorIs(tx.Rollback(expired), context.DeadlineExceeded, "context error has precedence")
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestContextErrors(t *testing.T) {
+	t.Parallel()
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	for _, tc := range []struct {
+		ctx context.Context
+		err error
+	}{{cancelled, context.Canceled}, {expired, context.DeadlineExceeded}} {
+		mock, _ := NewConn()
+		a := assert.New(t)
+		ctx := tc.ctx
+		mock.ExpectPing()
+		mock.ExpectExec("UPDATE").WillReturnResult(NewResult("UPDATE", 1))
+		mock.ExpectQuery("SELECT").WillReturnRows(NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery("SELECT").WillReturnRows(NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectBegin()
+		mock.ExpectPrepare("stmt", "SELECT")
+		mock.ExpectCopyFrom(pgx.Identifier{"t"}, []string{"id"}).WillReturnResult(1)
+		mock.ExpectSendBatchLen(1)
+
+		a.ErrorIs(mock.Ping(ctx), tc.err, "Ping")
+		_, err := mock.Exec(ctx, "UPDATE t SET id = 1")
+		a.ErrorIs(err, tc.err, "Exec")
+		_, err = mock.Query(ctx, "SELECT id FROM t")
+		a.ErrorIs(err, tc.err, "Query")
+		var id int
+		a.ErrorIs(mock.QueryRow(ctx, "SELECT id FROM t").Scan(&id), tc.err, "QueryRow")
+		_, err = mock.Begin(ctx)
+		a.ErrorIs(err, tc.err, "Begin")
+		_, err = mock.Prepare(ctx, "stmt", "SELECT")
+		a.ErrorIs(err, tc.err, "Prepare")
+		_, err = mock.CopyFrom(ctx, pgx.Identifier{"t"}, []string{"id"}, pgx.CopyFromRows([][]any{{1}}))
+		a.ErrorIs(err, tc.err, "CopyFrom")
+		batch := &pgx.Batch{}
+		batch.Queue("INSERT INTO t VALUES (1)")
+		_, err = mock.SendBatch(ctx, batch).Exec()
+		a.ErrorIs(err, tc.err, "SendBatch")
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestUnorderedUnexpectedCallError(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	mock, _ := NewConn()
+	mock.MatchExpectationsInOrder(false)
+	mock.ExpectPing()
+	mock.ExpectExec("DELETE FROM users").WithArgs(1).WillReturnResult(NewResult("DELETE", 1))
+
+	_, err := mock.Exec(ctx, "DELETE FROM users WHERE id = $1", 2)
+	a.ErrorContains(err, "call to method Exec() was not expected, closest mismatch: argument 0 expected [int - 1] does not match actual [int - 2]")
+	_, err = mock.Query(ctx, "SELECT 1")
+	a.EqualError(err, "call to method Query() was not expected")
+	a.NoError(mock.Ping(ctx))
+	_, err = mock.Exec(ctx, "DELETE FROM users WHERE id = $1", 1)
+	a.NoError(err)
+	a.EqualError(mock.Ping(ctx), "all expectations were already fulfilled, call to method Ping() was not expected")
+}
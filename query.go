@@ -3,6 +3,8 @@ package pgxmock
 import (
 	"fmt"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 )
 
@@ -42,7 +44,17 @@ func (f QueryMatcherFunc) Match(expectedSQL, actualSQL string) error {
 // QueryMatcherRegexp is the default SQL query matcher
 // used by pgxmock. It parses expectedSQL to a regular
 // expression and attempts to match actualSQL.
-var QueryMatcherRegexp QueryMatcher = QueryMatcherFunc(func(expectedSQL, actualSQL string) error {
+var QueryMatcherRegexp QueryMatcher = regexpMatcher{}
+
+// QueryMatcherEqual is the SQL query matcher
+// which simply tries a case sensitive match of
+// expected and actual SQL strings without whitespace.
+var QueryMatcherEqual QueryMatcher = equalMatcher{}
+
+type regexpMatcher struct{}
+
+// Match implements the QueryMatcher
+func (regexpMatcher) Match(expectedSQL, actualSQL string) error {
 	expect := stripQuery(expectedSQL)
 	actual := stripQuery(actualSQL)
 	re, err := regexp.Compile(expect)
@@ -53,16 +65,336 @@ var QueryMatcherRegexp QueryMatcher = QueryMatcherFunc(func(expectedSQL, actualS
 		return fmt.Errorf(`could not match actual sql: "%s" with expected regexp "%s"`, actual, re.String())
 	}
 	return nil
-})
+}
 
-// QueryMatcherEqual is the SQL query matcher
-// which simply tries a case sensitive match of
-// expected and actual SQL strings without whitespace.
-var QueryMatcherEqual QueryMatcher = QueryMatcherFunc(func(expectedSQL, actualSQL string) error {
+// String returns the name of matching semantics
+func (regexpMatcher) String() string {
+	return "regexp"
+}
+
+type equalMatcher struct{}
+
+// Match implements the QueryMatcher
+func (equalMatcher) Match(expectedSQL, actualSQL string) error {
 	expect := stripQuery(expectedSQL)
 	actual := stripQuery(actualSQL)
 	if actual != expect {
 		return fmt.Errorf(`actual sql: "%s" does not equal to expected "%s"`, actual, expect)
 	}
 	return nil
-})
+}
+
+// String returns the name of matching semantics
+func (equalMatcher) String() string {
+	return "equal"
+}
+
+// QueryMatcherPlaceholderNormalized is the SQL query matcher which
+// renumbers positional placeholders, e.g. $1, in the order of appearance
+// before a case sensitive match of SQL strings without whitespace. Actual
+// arguments are reordered accordingly, so queries produced by builders
+// reordering clauses are matched, e.g. "a = $2 AND b = $1" with args (y, x)
+// matches expected "a = $1 AND b = $2" with args (x, y).
+var QueryMatcherPlaceholderNormalized QueryMatcher = placeholderNormalizedMatcher{}
+
+type placeholderNormalizedMatcher struct{}
+
+// Match implements the QueryMatcher
+func (placeholderNormalizedMatcher) Match(expectedSQL, actualSQL string) error {
+	expect := stripQuery(normalizePlaceholders(expectedSQL))
+	actual := stripQuery(normalizePlaceholders(actualSQL))
+	if actual != expect {
+		return fmt.Errorf(`actual sql: "%s" does not equal to expected "%s" after placeholders normalization`, actual, expect)
+	}
+	return nil
+}
+
+// String returns the name of matching semantics
+func (placeholderNormalizedMatcher) String() string {
+	return "placeholder normalized"
+}
+
+// searchPathMatcher matches SET search_path statements setting the same
+// list of schemas, regardless of the syntax variant and identifier quoting
+type searchPathMatcher struct{}
+
+var reSearchPath = regexp.MustCompile(`(?is)^\s*SET\s+(?:SESSION\s+|LOCAL\s+)?search_path\s*(?:TO|=)\s*(.*?)\s*;?\s*$`)
+
+// Match implements the QueryMatcher
+func (searchPathMatcher) Match(expectedSQL, actualSQL string) error {
+	expect, _ := searchPath(expectedSQL)
+	actual, ok := searchPath(actualSQL)
+	if !ok {
+		return fmt.Errorf(`actual sql: "%s" does not set search_path`, actualSQL)
+	}
+	if !slices.Equal(actual, expect) {
+		return fmt.Errorf(`actual sql: "%s" sets search_path to %v, but %v expected`, actualSQL, actual, expect)
+	}
+	return nil
+}
+
+// String returns the name of matching semantics
+func (searchPathMatcher) String() string {
+	return "search_path"
+}
+
+// searchPath returns schemas set by the SET search_path statement
+func searchPath(sql string) (schemas []string, ok bool) {
+	m := reSearchPath.FindStringSubmatch(sql)
+	if m == nil {
+		return nil, false
+	}
+	for _, schema := range strings.Split(m[1], ",") {
+		schema = strings.TrimSpace(schema)
+		if n := len(schema); n > 1 && (schema[0] == '"' || schema[0] == '\'') && schema[n-1] == schema[0] {
+			schema = schema[1 : n-1]
+		} else {
+			schema = strings.ToLower(schema)
+		}
+		schemas = append(schemas, schema)
+	}
+	return schemas, true
+}
+
+// multiStatementMatcher splits the actual SQL into statements and
+// matches each of them against the patterns in order
+type multiStatementMatcher struct {
+	patterns []string
+	matcher  QueryMatcher
+}
+
+// Match implements the QueryMatcher, expectedSQL is ignored
+func (m multiStatementMatcher) Match(_, actualSQL string) error {
+	statements := splitStatements(actualSQL)
+	if len(statements) != len(m.patterns) {
+		return fmt.Errorf(`actual sql: "%s" has %d statements, but %d expected`, actualSQL, len(statements), len(m.patterns))
+	}
+	for i, pattern := range m.patterns {
+		if err := m.matcher.Match(pattern, statements[i]); err != nil {
+			return fmt.Errorf("statement %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// String returns the name of matching semantics
+func (m multiStatementMatcher) String() string {
+	return "multi-statement " + matcherName(m.matcher)
+}
+
+// splitStatements splits SQL on semicolons used in the code of SQL, see
+// segmentSQL, empty statements are omitted
+func splitStatements(sql string) (statements []string) {
+	var stmt strings.Builder
+	add := func() {
+		if s := strings.TrimSpace(stmt.String()); s != "" {
+			statements = append(statements, s)
+		}
+		stmt.Reset()
+	}
+	for _, s := range segmentSQL(sql) {
+		if !s.code {
+			stmt.WriteString(s.text)
+			continue
+		}
+		parts := strings.Split(s.text, ";")
+		for i, part := range parts {
+			stmt.WriteString(part)
+			if i < len(parts)-1 {
+				add()
+			}
+		}
+	}
+	add()
+	return statements
+}
+
+var reDollarQuote = regexp.MustCompile(`^\$(?:[A-Za-z_][A-Za-z_0-9]*)?\$`)
+
+// sqlSegment is a part of SQL returned by segmentSQL
+type sqlSegment struct {
+	text string
+	code bool // false for literals, quoted identifiers and comments
+}
+
+// segmentSQL splits SQL into code and the parts where placeholders and
+// semicolons have no meaning: string literals including escape strings,
+// quoted identifiers, dollar-quoted strings, line and block comments
+func segmentSQL(sql string) (segments []sqlSegment) {
+	start := 0
+	add := func(end int, code bool) {
+		if end > start {
+			segments = append(segments, sqlSegment{sql[start:end], code})
+		}
+		start = end
+	}
+	for i := 0; i < len(sql); {
+		var end int
+		switch ch := sql[i]; {
+		case ch == '\'' || ch == '"':
+			escapes := ch == '\'' && i > 0 && (sql[i-1] == 'E' || sql[i-1] == 'e') && (i < 2 || !isIdentChar(sql[i-2]))
+			end = quotedEnd(sql, i, ch, escapes)
+		case ch == '$' && (i == 0 || !isIdentChar(sql[i-1])):
+			if tag := reDollarQuote.FindString(sql[i:]); tag != "" {
+				if k := strings.Index(sql[i+len(tag):], tag); k >= 0 {
+					end = i + len(tag) + k + len(tag)
+				} else {
+					end = len(sql)
+				}
+			}
+		case strings.HasPrefix(sql[i:], "--"):
+			if k := strings.IndexByte(sql[i:], '\n'); k >= 0 {
+				end = i + k
+			} else {
+				end = len(sql)
+			}
+		case strings.HasPrefix(sql[i:], "/*"):
+			end = commentEnd(sql, i)
+		}
+		if end == 0 {
+			i++
+			continue
+		}
+		add(i, true)
+		add(end, false)
+		i = end
+	}
+	add(len(sql), true)
+	return segments
+}
+
+// quotedEnd returns the index after the closing quote, doubled quotes
+// and backslash escapes of escape strings are a part of the literal
+func quotedEnd(sql string, i int, quote byte, escapes bool) int {
+	for j := i + 1; j < len(sql); j++ {
+		switch {
+		case escapes && sql[j] == '\\':
+			j++
+		case sql[j] == quote:
+			if j+1 < len(sql) && sql[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(sql)
+}
+
+// commentEnd returns the index after the block comment, comments may be nested
+func commentEnd(sql string, i int) int {
+	depth := 0
+	for j := i; j < len(sql)-1; j++ {
+		switch sql[j : j+2] {
+		case "/*":
+			depth++
+			j++
+		case "*/":
+			depth--
+			j++
+			if depth == 0 {
+				return j + 1
+			}
+		}
+	}
+	return len(sql)
+}
+
+func isIdentChar(ch byte) bool {
+	return ch == '_' || ch >= '0' && ch <= '9' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= 0x80
+}
+
+// sqlCode returns SQL with everything except code replaced by spaces,
+// so positions of the code are kept
+func sqlCode(sql string) string {
+	var b strings.Builder
+	for _, s := range segmentSQL(sql) {
+		if s.code {
+			b.WriteString(s.text)
+		} else {
+			b.WriteString(strings.Repeat(" ", len(s.text)))
+		}
+	}
+	return b.String()
+}
+
+// renumberPlaceholders replaces numbers of positional placeholders used
+// in the code of SQL, see segmentSQL, with the result of fn
+func renumberPlaceholders(sql string, fn func(n int) int) string {
+	var b strings.Builder
+	for _, s := range segmentSQL(sql) {
+		if !s.code {
+			b.WriteString(s.text)
+			continue
+		}
+		code := s.text
+		for i := 0; i < len(code); i++ {
+			ch := code[i]
+			if ch == '$' && (i == 0 || !isIdentChar(code[i-1])) {
+				j := i + 1
+				for j < len(code) && code[j] >= '0' && code[j] <= '9' {
+					j++
+				}
+				if n, err := strconv.Atoi(code[i+1 : j]); err == nil {
+					b.WriteString("$" + strconv.Itoa(fn(n)))
+					i = j - 1
+					continue
+				}
+			}
+			b.WriteByte(ch)
+		}
+	}
+	return b.String()
+}
+
+// placeholderOrder returns distinct numbers of positional placeholders
+// in the order of appearance
+func placeholderOrder(sql string) (order []int) {
+	renumberPlaceholders(sql, func(n int) int {
+		if !slices.Contains(order, n) {
+			order = append(order, n)
+		}
+		return n
+	})
+	return order
+}
+
+// normalizePlaceholders renumbers positional placeholders in the order of appearance
+func normalizePlaceholders(sql string) string {
+	var order []int
+	return renumberPlaceholders(sql, func(n int) int {
+		k := slices.Index(order, n)
+		if k < 0 {
+			order = append(order, n)
+			k = len(order) - 1
+		}
+		return k + 1
+	})
+}
+
+// reorderArgs reorders actual arguments to follow placeholders numbering of
+// the expected SQL, arguments are returned as is if placeholders don't correspond
+func reorderArgs(expectedSQL, actualSQL string, args []interface{}) []interface{} {
+	expected, actual := placeholderOrder(expectedSQL), placeholderOrder(actualSQL)
+	if len(expected) != len(actual) || len(actual) != len(args) {
+		return args
+	}
+	reordered := make([]interface{}, len(args))
+	for k, n := range actual {
+		if n < 1 || n > len(args) || expected[k] < 1 || expected[k] > len(args) {
+			return args
+		}
+		reordered[expected[k]-1] = args[n-1]
+	}
+	return reordered
+}
+
+// matcherName returns the name of the QueryMatcher to be shown in
+// expectation string representation. Custom matchers may implement
+// fmt.Stringer to be described properly
+func matcherName(m QueryMatcher) string {
+	if s, ok := m.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return "custom"
+}
@@ -3,6 +3,8 @@ package pgxmock
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -122,3 +124,135 @@ func TestQueryMatcherEqual(t *testing.T) {
 		}
 	}
 }
+
+func TestQueryMatcherName(t *testing.T) {
+	cases := map[string]QueryMatcher{
+		"regexp":                 QueryMatcherRegexp,
+		"equal":                  QueryMatcherEqual,
+		"placeholder normalized": QueryMatcherPlaceholderNormalized,
+		"custom":                 QueryMatcherFunc(func(string, string) error { return nil }),
+	}
+	for expected, m := range cases {
+		if name := matcherName(m); name != expected {
+			t.Errorf("expected matcher name '%s', but got '%s'", expected, name)
+		}
+	}
+
+	mock, _ := NewConn(QueryMatcherOption(QueryMatcherEqual))
+	if s := mock.ExpectQuery("SELECT 1").String(); !strings.Contains(s, "matches sql (equal): 'SELECT 1'") {
+		t.Errorf("expectation must show equal matcher, but got: %s", s)
+	}
+}
+
+func TestQueryMatcherPlaceholderNormalized(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		expected string
+		actual   string
+		match    bool
+	}{
+		{"WHERE a = $1 AND b = $2", "WHERE a = $2 AND b = $1", true},
+		{"WHERE a = $1 AND b = $2 OR c = $1", "WHERE a = $5 AND b = $3 OR c = $5", true},
+		{"WHERE a = $1 AND b = $2", "WHERE a = $1 AND b = $1", false},
+		{"WHERE a = '$1'", "WHERE a = '$2'", false},
+		{"WHERE a = $1", "where a = $1", false},
+	}
+	for i, c := range cases {
+		err := QueryMatcherPlaceholderNormalized.Match(c.expected, c.actual)
+		if c.match && err != nil {
+			t.Errorf("got unexpected error \"%v\" at %d case", err, i)
+		}
+		if !c.match && err == nil {
+			t.Errorf("got no error, but expected mismatch at %d case", i)
+		}
+	}
+
+	mock, _ := NewConn(QueryMatcherOption(QueryMatcherPlaceholderNormalized))
+	mock.ExpectExec("UPDATE orders SET status = $1 WHERE id = $2").
+		WithArgs("paid", 42).
+		WillReturnResult(NewResult("UPDATE", 1))
+	if _, err := mock.Exec(context.Background(), "UPDATE orders SET status = $2 WHERE id = $1", 42, "paid"); err != nil {
+		t.Errorf("arguments must be reordered following placeholders: %s", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		sql        string
+		statements []string
+	}{
+		{"SELECT 1", []string{"SELECT 1"}},
+		{"A; B;\n C;", []string{"A", "B", "C"}},
+		{"INSERT INTO t VALUES ('a;b'); SELECT \"x;y\"", []string{"INSERT INTO t VALUES ('a;b')", `SELECT "x;y"`}},
+		{"CREATE FUNCTION f() AS $$ BEGIN; END $$; DO $body$ ; $body$", []string{"CREATE FUNCTION f() AS $$ BEGIN; END $$", "DO $body$ ; $body$"}},
+		{"SELECT $1; ;", []string{"SELECT $1"}},
+		{"SELECT 1 -- first; second\n; /* a; /* b; */ c; */ SELECT 2", []string{"SELECT 1 -- first; second", "/* a; /* b; */ c; */ SELECT 2"}},
+		{`SELECT E'\';' ; SELECT 'it''s; fine'`, []string{`SELECT E'\';'`, "SELECT 'it''s; fine'"}},
+	}
+	for i, c := range cases {
+		if statements := splitStatements(c.sql); !reflect.DeepEqual(statements, c.statements) {
+			t.Errorf("expected %q, but got %q at %d case", c.statements, statements, i)
+		}
+	}
+}
+
+func TestExpectExecMulti(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	e := mock.ExpectExecMulti("CREATE TABLE users", "CREATE INDEX", "INSERT INTO users").
+		WillReturnResults(NewResult("CREATE TABLE", 0), NewResult("CREATE INDEX", 0), NewResult("INSERT", 1))
+	if !strings.Contains(e.String(), "multi-statement regex") {
+		t.Errorf("matcher is not described: %s", e)
+	}
+	script := "CREATE TABLE users (id int, name text);\nCREATE INDEX ON users (name);\nINSERT INTO users VALUES (1, 'a;b');"
+	if _, err := mock.Exec(context.Background(), "CREATE TABLE users; INSERT INTO users"); err == nil {
+		t.Error("statement count mismatch expected")
+	}
+	if _, err := mock.Exec(context.Background(), "CREATE TABLE users; CREATE VIEW v; INSERT INTO users"); err == nil ||
+		!strings.Contains(err.Error(), "statement 2:") {
+		t.Errorf("second statement mismatch expected, got %v", err)
+	}
+	if _, err := mock.Exec(context.Background(), script); err != nil {
+		t.Error(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestExpectQueryE(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	if _, err := mock.ExpectQueryE("SELECT [invalid("); err == nil ||
+		!strings.Contains(err.Error(), "invalid expected sql regexp 'SELECT [invalid('") {
+		t.Errorf("expected invalid regexp error, got %v", err)
+	}
+	if _, err := mock.ExpectExecE("DELETE FROM (users"); err == nil {
+		t.Error("expected invalid regexp error")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("invalid expectations must not be set: %s", err)
+	}
+	e, err := mock.ExpectExecE(`DELETE FROM users WHERE id = \$1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.WithArgs(1).WillReturnResult(NewResult("DELETE", 1))
+	if _, err = mock.Exec(context.Background(), "DELETE FROM users WHERE id = $1", 1); err != nil {
+		t.Error(err)
+	}
+
+	mock.ExpectQuery("SELECT [invalid(")
+	if _, err = mock.Query(context.Background(), "SELECT 1"); err == nil {
+		t.Errorf("expected invalid regexp error at call time, got %v", err)
+	}
+
+	mock, _ = NewConn(QueryMatcherOption(QueryMatcherEqual))
+	if _, err = mock.ExpectQueryE("SELECT [invalid("); err != nil {
+		t.Errorf("no regexp must be compiled for other matchers: %s", err)
+	}
+}
@@ -0,0 +1,260 @@
+package pgxmock
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	pgx "github.com/jackc/pgx/v5"
+	pgconn "github.com/jackc/pgx/v5/pgconn"
+)
+
+// Querier is the part of *pgx.Conn, *pgxpool.Pool or pgx.Tx used by Recorder
+type Querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// Recording is a Query() or Exec() call captured by Recorder,
+// it may be serialized to JSON and replayed as an expectation
+type Recording struct {
+	Method     string   `json:"method"` // Query or Exec
+	SQL        string   `json:"sql"`
+	Args       []any    `json:"args,omitempty"`
+	Columns    []string `json:"columns,omitempty"`
+	Rows       [][]any  `json:"rows,omitempty"`
+	CommandTag string   `json:"command_tag,omitempty"`
+	Error      string   `json:"error,omitempty"`
+	ErrorCode  string   `json:"error_code,omitempty"` // SQLSTATE of *pgconn.PgError
+}
+
+func (rec *Recording) setError(err error) {
+	rec.Error = err.Error()
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		rec.Error, rec.ErrorCode = pgErr.Message, pgErr.Code
+	}
+}
+
+func (rec *Recording) err() error {
+	if rec.ErrorCode != "" {
+		return &pgconn.PgError{Severity: "ERROR", Code: rec.ErrorCode, Message: rec.Error}
+	}
+	return errors.New(rec.Error)
+}
+
+// Recorder wraps a real connection and captures Query(), QueryRow() and
+// Exec() calls with arguments and results, so test fixtures may be
+// bootstrapped from actual runs and replayed against the mock by Replay
+type Recorder struct {
+	conn       Querier
+	mu         sync.Mutex
+	recordings []Recording
+}
+
+// NewRecorder returns Recorder capturing calls to the connection
+func NewRecorder(conn Querier) *Recorder {
+	return &Recorder{conn: conn}
+}
+
+// add reserves the place for the recording in the order of calls
+func (r *Recorder) add(rec Recording) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.recordings = append(r.recordings, rec)
+	return len(r.recordings) - 1
+}
+
+func (r *Recorder) set(idx int, rec Recording) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.recordings[idx] = rec
+}
+
+// Exec executes the statement using the wrapped connection and records the result
+func (r *Recorder) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	rec := Recording{Method: "Exec", SQL: sql, Args: args}
+	tag, err := r.conn.Exec(ctx, sql, args...)
+	if err != nil {
+		rec.setError(err)
+	}
+	rec.CommandTag = tag.String()
+	r.add(rec)
+	return tag, err
+}
+
+// Query executes the query using the wrapped connection, returned rows are
+// recorded as they are read, the recording is complete once rows are closed
+func (r *Recorder) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	rec := Recording{Method: "Query", SQL: sql, Args: args}
+	rows, err := r.conn.Query(ctx, sql, args...)
+	if err != nil {
+		rec.setError(err)
+		r.add(rec)
+		return rows, err
+	}
+	rr := &recordingRows{Rows: rows, recorder: r, rec: rec}
+	rr.idx = r.add(rec)
+	return rr, nil
+}
+
+// QueryRow executes the query the same way as Query and scans the first row
+func (r *Recorder) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	rows, err := r.Query(ctx, sql, args...)
+	if err != nil {
+		return errRow{err}
+	}
+	return recordingRow{rows}
+}
+
+// Recordings returns calls captured so far in the order they were made
+func (r *Recorder) Recordings() []Recording {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Recording{}, r.recordings...)
+}
+
+// WriteRecordings serializes captured calls to JSON consumable by LoadRecordings
+func (r *Recorder) WriteRecordings(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(r.Recordings())
+}
+
+// LoadRecordings deserializes calls written by WriteRecordings. Note that
+// row values are decoded as JSON types, e.g. numbers become float64
+func LoadRecordings(r io.Reader) ([]Recording, error) {
+	var recordings []Recording
+	if err := json.NewDecoder(r).Decode(&recordings); err != nil {
+		return nil, fmt.Errorf("error loading recordings: %w", err)
+	}
+	return recordings, nil
+}
+
+// Replay sets expectations of the mock reproducing recorded calls in the
+// same order. SQL is matched with QueryMatcherEqual, arguments are matched
+// if their JSON representation is the same as of the recorded ones.
+func Replay(mock Expecter, recordings ...Recording) error {
+	for _, rec := range recordings {
+		args := make([]any, len(rec.Args))
+		for i, arg := range rec.Args {
+			if _, ok := arg.(pgx.QueryRewriter); ok {
+				args[i] = arg
+				continue
+			}
+			args[i] = recordedArg{arg}
+		}
+		switch rec.Method {
+		case "Exec":
+			e := mock.ExpectExec(rec.SQL).WithQueryMatcher(QueryMatcherEqual).WithArgs(args...)
+			if rec.Error != "" {
+				e.WillReturnError(rec.err())
+				continue
+			}
+			e.WillReturnResult(pgconn.NewCommandTag(rec.CommandTag))
+		case "Query":
+			e := mock.ExpectQuery(rec.SQL).WithQueryMatcher(QueryMatcherEqual).WithArgs(args...)
+			if rec.Error != "" && rec.Columns == nil {
+				e.WillReturnError(rec.err())
+				continue
+			}
+			rows := NewRows(rec.Columns).AddRows(rec.Rows...)
+			if rec.CommandTag != "" {
+				rows.AddCommandTag(pgconn.NewCommandTag(rec.CommandTag))
+			}
+			if rec.Error != "" {
+				rows.RowError(len(rec.Rows), rec.err())
+			}
+			e.WillReturnRows(rows)
+		default:
+			return fmt.Errorf("unsupported method '%s' of recording: %s", rec.Method, rec.SQL)
+		}
+	}
+	return nil
+}
+
+// recordedArg matches arguments having the same JSON representation,
+// so recordings loaded from JSON match arguments of any numeric type
+type recordedArg struct {
+	value any
+}
+
+// Match implements the Argument interface
+func (a recordedArg) Match(v any) bool {
+	expected, err := json.Marshal(a.value)
+	if err != nil {
+		return false
+	}
+	actual, err := json.Marshal(v)
+	return err == nil && bytes.Equal(expected, actual)
+}
+
+// String returns the recorded value
+func (a recordedArg) String() string {
+	return fmt.Sprintf("%+v", a.value)
+}
+
+// recordingRows captures values of rows as they are read
+type recordingRows struct {
+	pgx.Rows
+	recorder *Recorder
+	rec      Recording
+	idx      int
+	done     bool
+}
+
+func (rr *recordingRows) Next() bool {
+	if !rr.Rows.Next() {
+		rr.finish()
+		return false
+	}
+	if values, err := rr.Rows.Values(); err == nil {
+		rr.rec.Rows = append(rr.rec.Rows, values)
+	}
+	return true
+}
+
+func (rr *recordingRows) Close() {
+	rr.Rows.Close()
+	rr.finish()
+}
+
+// finish completes the recording once rows are exhausted or closed
+func (rr *recordingRows) finish() {
+	if rr.done {
+		return
+	}
+	rr.done = true
+	for _, fd := range rr.Rows.FieldDescriptions() {
+		rr.rec.Columns = append(rr.rec.Columns, fd.Name)
+	}
+	if err := rr.Rows.Err(); err != nil {
+		rr.rec.setError(err)
+	}
+	rr.rec.CommandTag = rr.Rows.CommandTag().String()
+	rr.recorder.set(rr.idx, rr.rec)
+}
+
+// recordingRow scans the first row the same way pgx.Row does
+type recordingRow struct {
+	rows pgx.Rows
+}
+
+func (r recordingRow) Scan(dest ...any) error {
+	defer r.rows.Close()
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+		return pgx.ErrNoRows
+	}
+	if err := r.rows.Scan(dest...); err != nil {
+		return err
+	}
+	r.rows.Close()
+	return r.rows.Err()
+}
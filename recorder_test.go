@@ -0,0 +1,80 @@
+package pgxmock
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	pgconn "github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	ctx := context.Background()
+	// the mock plays the real database role here
+	db, _ := NewConn()
+	db.ExpectExec("UPDATE users").WithArgs(42).WillReturnResult(NewResult("UPDATE", 1))
+	db.ExpectQuery("SELECT id, name FROM users").
+		WillReturnRows(NewRows([]string{"id", "name"}).AddRow(1, "john").AddRow(2, "mary"))
+	db.ExpectQuery("SELECT name FROM users").WithArgs(3).
+		WillReturnRows(NewRows([]string{"name"}).AddRow("bob"))
+	db.ExpectExec("DELETE FROM users").WillReturnError(&pgconn.PgError{Code: "23503", Message: "foreign key violation"})
+
+	rec := NewRecorder(db)
+	tag, err := rec.Exec(ctx, "UPDATE users SET active = true WHERE id = $1", 42)
+	a.NoError(err)
+	a.Equal("UPDATE 1", tag.String())
+	rows, err := rec.Query(ctx, "SELECT id, name FROM users")
+	a.NoError(err)
+	var names []string
+	for rows.Next() {
+		var id int
+		var name string
+		a.NoError(rows.Scan(&id, &name))
+		names = append(names, name)
+	}
+	rows.Close()
+	a.Equal([]string{"john", "mary"}, names)
+	var name string
+	a.NoError(rec.QueryRow(ctx, "SELECT name FROM users WHERE id = $1", 3).Scan(&name))
+	a.Equal("bob", name)
+	_, err = rec.Exec(ctx, "DELETE FROM users")
+	a.Error(err)
+	a.NoError(db.ExpectationsWereMet())
+
+	recordings := rec.Recordings()
+	a.Len(recordings, 4)
+	a.Equal([][]any{{1, "john"}, {2, "mary"}}, recordings[1].Rows)
+	a.Equal("23503", recordings[3].ErrorCode)
+
+	var buf bytes.Buffer
+	a.NoError(rec.WriteRecordings(&buf))
+	loaded, err := LoadRecordings(&buf)
+	a.NoError(err)
+
+	mock, _ := NewConn()
+	a.NoError(Replay(mock, loaded...))
+	_, err = mock.Exec(ctx, "UPDATE users SET active = true WHERE id = $1", 42)
+	a.NoError(err, "recorded args expected to match regardless of JSON number type")
+	rows, err = mock.Query(ctx, "SELECT id, name FROM users")
+	a.NoError(err)
+	for rows.Next() {
+		var id float64
+		a.NoError(rows.Scan(&id, &name))
+	}
+	rows.Close()
+	a.Equal("mary", name)
+	a.NoError(mock.QueryRow(ctx, "SELECT name FROM users WHERE id = $1", 3).Scan(&name))
+	_, err = mock.Exec(ctx, "DELETE FROM users")
+	var pgErr *pgconn.PgError
+	a.True(errors.As(err, &pgErr))
+	a.Equal("23503", pgErr.Code)
+	a.NoError(mock.ExpectationsWereMet())
+
+	a.Error(Replay(mock, Recording{Method: "CopyFrom"}))
+	_, err = LoadRecordings(bytes.NewBufferString("{"))
+	a.Error(err)
+}
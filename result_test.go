@@ -1,7 +1,10 @@
 package pgxmock
 
 import (
+	"context"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestShouldReturnValidSqlDriverResult(t *testing.T) {
@@ -14,3 +17,23 @@ func TestShouldReturnValidSqlDriverResult(t *testing.T) {
 		t.Errorf("expected affected rows to be 2, but got: %d", affected)
 	}
 }
+
+func TestWillReturnZeroAffected(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	mock, _ := NewConn()
+	mock.ExpectExec("INSERT").WillReturnZeroAffected("INSERT")
+	mock.ExpectExec("UPDATE").WillReturnZeroAffected("UPDATE")
+
+	tag, err := mock.Exec(context.Background(), "INSERT INTO users VALUES (1) ON CONFLICT DO NOTHING")
+	a.NoError(err)
+	a.Equal("INSERT 0 0", tag.String())
+	a.True(tag.Insert())
+	a.Zero(tag.RowsAffected())
+	tag, err = mock.Exec(context.Background(), "UPDATE users SET name = 'john' WHERE false")
+	a.NoError(err)
+	a.Equal("UPDATE 0", tag.String())
+	a.True(tag.Update())
+	a.Zero(tag.RowsAffected())
+	a.NoError(mock.ExpectationsWereMet())
+}
@@ -1,14 +1,20 @@
 package pgxmock
 
 import (
+	"context"
+	"database/sql"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 // CSVColumnParser is a function which converts trimmed csv
@@ -26,19 +32,33 @@ type rowSets struct {
 	sets     []*Rows
 	RowSetNo int
 	ex       *ExpectedQuery
+	ctx      context.Context // context of the Query() call
+	err      error
 }
 
+var errNoRow = errors.New("no row available, call Next() first")
+
+// Conn returns nil, since there is no underlying *pgx.Conn
 func (rs *rowSets) Conn() *pgx.Conn {
 	return nil
 }
 
 func (rs *rowSets) Err() error {
+	if rs.err != nil {
+		return rs.err
+	}
 	r := rs.sets[rs.RowSetNo]
 	return r.nextErr[r.recNo-1]
 }
 
+// CommandTag returns the tag set by AddCommandTag, otherwise the same
+// as PostgreSQL does "SELECT n" is returned for results with columns
 func (rs *rowSets) CommandTag() pgconn.CommandTag {
-	return rs.sets[rs.RowSetNo].commandTag
+	r := rs.sets[rs.RowSetNo]
+	if r.commandTag.String() == "" && len(r.defs) > 0 {
+		return NewResult("SELECT", int64(len(r.rows)))
+	}
+	return r.commandTag
 }
 
 func (rs *rowSets) FieldDescriptions() []pgconn.FieldDescription {
@@ -49,24 +69,72 @@ func (rs *rowSets) FieldDescriptions() []pgconn.FieldDescription {
 // 	return rs.sets[rs.pos].cols
 // }
 
+// Close marks rows as closed, the error set by CloseError
+// is returned by Err() afterwards if not all rows were read
 func (rs *rowSets) Close() {
-	rs.ex.rowsWereClosed = true
-	// return rs.sets[rs.pos].closeErr
+	if rs.ex != nil {
+		rs.ex.rowsWereClosed = true
+	}
+	if r := rs.sets[rs.RowSetNo]; r.closeErr != nil && r.recNo <= len(r.rows) && rs.err == nil {
+		rs.err = r.closeErr
+	}
 }
 
 // advances to next row
 func (rs *rowSets) Next() bool {
 	r := rs.sets[rs.RowSetNo]
+	if r.recNo < len(r.rows) && !rs.waitForRow(r) {
+		return false
+	}
 	r.recNo++
 	return r.recNo <= len(r.rows)
 }
 
+// waitForRow delays the next row if planned, returns false
+// if the context of the Query() call is done
+func (rs *rowSets) waitForRow(r *Rows) bool {
+	ctx := rs.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := ctx.Err(); err != nil {
+		rs.err = err
+		return false
+	}
+	var delay time.Duration
+	if rs.ex != nil {
+		delay = rs.ex.rowDelay
+	}
+	if r.throttleDelay > 0 && r.recNo >= r.throttleAfter {
+		delay = r.throttleDelay
+	}
+	if delay <= 0 {
+		return true
+	}
+	select {
+	case <-time.After(delay):
+		return true
+	case <-ctx.Done():
+		rs.err = ctx.Err()
+		return false
+	}
+}
+
 // Values returns the decoded row values. As with Scan(), it is an error to
 // call Values without first calling Next() and checking that it returned
-// true.
+// true. Values are returned with the same Go types and in the same order
+// as passed to AddRow(), NULL values are returned as nil. Column type OIDs
+// do not affect returned values, since there is nothing to decode.
 func (rs *rowSets) Values() ([]interface{}, error) {
 	r := rs.sets[rs.RowSetNo]
-	return r.rows[r.recNo-1], r.nextErr[r.recNo-1]
+	if r.recNo < 1 || r.recNo > len(r.rows) {
+		return nil, errNoRow
+	}
+	values := slices.Clone(r.rows[r.recNo-1])
+	for i, v := range values {
+		values[i] = r.value(i, v)
+	}
+	return values, r.nextErr[r.recNo-1]
 }
 
 func (rs *rowSets) Scan(dest ...interface{}) error {
@@ -82,7 +150,17 @@ func (rs *rowSets) Scan(dest ...interface{}) error {
 	if len(r.rows) == 0 {
 		return pgx.ErrNoRows
 	}
+	if r.recNo < 1 || r.recNo > len(r.rows) {
+		if rs.err != nil {
+			return rs.err
+		}
+		return errNoRow
+	}
+	if len(r.rows[r.recNo-1]) != len(r.defs) {
+		return fmt.Errorf("Malformed row with %d values for %d columns", len(r.rows[r.recNo-1]), len(r.defs))
+	}
 	for i, col := range r.rows[r.recNo-1] {
+		col = r.value(i, col)
 		if dest[i] == nil {
 			//behave compatible with pgx
 			continue
@@ -92,7 +170,9 @@ func (rs *rowSets) Scan(dest ...interface{}) error {
 			return fmt.Errorf("Destination argument must be a pointer for column %s", r.defs[i].Name)
 		}
 		if col == nil {
-			dest[i] = nil
+			if err := rs.scanNullValue(i, r.defs[i], dest[i]); err != nil {
+				return err
+			}
 			continue
 		}
 		val := reflect.ValueOf(col)
@@ -102,26 +182,229 @@ func (rs *rowSets) Scan(dest ...interface{}) error {
 			} else {
 				return fmt.Errorf("Cannot set destination value for column %s", r.defs[i].Name)
 			}
-		} else {
-			// Try to use Scanner interface
-			scanner, ok := destVal.Interface().(interface{ Scan(interface{}) error })
+		} else if err := rs.scanConverted(r.defs[i], col, dest[i]); err != nil {
+			return err
+		}
+	}
+	return r.nextErr[r.recNo-1]
+}
 
-			if !ok {
-				return fmt.Errorf("Destination kind '%v' not supported for value kind '%v' of column '%s'",
-					destVal.Elem().Kind(), val.Kind(), string(r.defs[i].Name))
-			}
-			if err := scanner.Scan(val.Interface()); err != nil {
-				return fmt.Errorf("Scanning value error for column '%s': %w", string(r.defs[i].Name), err)
+// scanConverted scans the value not assignable to the destination using
+// Scanner interfaces, composite values or the type registered for the column
+func (rs *rowSets) scanConverted(col pgconn.FieldDescription, src, dest any) error {
+	destVal := reflect.ValueOf(dest).Elem()
+	ok, err := scanCustom(dest, src)
+	if !ok {
+		ok, err = scanComposite(destVal, src)
+	}
+	if !ok {
+		ok, err = rs.scanTypeMap(col.DataTypeOID, src, dest)
+	}
+	if !ok {
+		return fmt.Errorf("Destination kind '%v' not supported for value kind '%v' of column '%s'",
+			destVal.Kind(), reflect.ValueOf(src).Kind(), col.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("Scanning value error for column '%s': %w", col.Name, err)
+	}
+	return nil
+}
+
+// scanCustom passes the value to the sql.Scanner or pgtype scanner
+// interfaces implemented by the destination, the same way pgx does for
+// custom types. Returns false if no interface accepts the value.
+func scanCustom(dest, src any) (bool, error) {
+	if s, ok := dest.(sql.Scanner); ok {
+		return true, s.Scan(src)
+	}
+	if s, ok := dest.(pgtype.TextScanner); ok {
+		switch v := src.(type) {
+		case nil:
+			return true, s.ScanText(pgtype.Text{})
+		case string:
+			return true, s.ScanText(pgtype.Text{String: v, Valid: true})
+		}
+	}
+	if s, ok := dest.(pgtype.Int64Scanner); ok {
+		if src == nil {
+			return true, s.ScanInt64(pgtype.Int8{})
+		}
+		if v := reflect.ValueOf(src); v.CanInt() {
+			return true, s.ScanInt64(pgtype.Int8{Int64: v.Int(), Valid: true})
+		}
+	}
+	if s, ok := dest.(pgtype.Float64Scanner); ok {
+		if src == nil {
+			return true, s.ScanFloat64(pgtype.Float8{})
+		}
+		if v := reflect.ValueOf(src); v.CanFloat() {
+			return true, s.ScanFloat64(pgtype.Float8{Float64: v.Float(), Valid: true})
+		}
+	}
+	if s, ok := dest.(pgtype.BoolScanner); ok {
+		switch v := src.(type) {
+		case nil:
+			return true, s.ScanBool(pgtype.Bool{})
+		case bool:
+			return true, s.ScanBool(pgtype.Bool{Bool: v, Valid: true})
+		}
+	}
+	if s, ok := dest.(pgtype.TimestamptzScanner); ok {
+		switch v := src.(type) {
+		case nil:
+			return true, s.ScanTimestamptz(pgtype.Timestamptz{})
+		case time.Time:
+			return true, s.ScanTimestamptz(pgtype.Timestamptz{Time: v, Valid: true})
+		}
+	}
+	if s, ok := dest.(pgtype.BytesScanner); ok {
+		switch v := src.(type) {
+		case nil:
+			return true, s.ScanBytes(nil)
+		case []byte:
+			return true, s.ScanBytes(v)
+		}
+	}
+	return false, nil
+}
+
+// scanComposite sets composite values, []any with fields of a composite
+// type, to the struct or values of the composite array, [][]any or *Rows,
+// to the slice of structs or struct pointers. Exported struct fields are
+// set in order of the composite fields, the same way as pgx does.
+func scanComposite(dest reflect.Value, src any) (bool, error) {
+	var elems [][]any
+	switch src := src.(type) {
+	case []any:
+		if structType(dest.Type()) == nil {
+			return false, nil
+		}
+		return true, setComposite(dest, src)
+	case [][]any:
+		elems = src
+	case *Rows:
+		elems = src.rows
+	default:
+		return false, nil
+	}
+	if dest.Kind() != reflect.Slice || structType(dest.Type().Elem()) == nil {
+		return false, nil
+	}
+	slice := reflect.MakeSlice(dest.Type(), len(elems), len(elems))
+	for i, elem := range elems {
+		if err := setComposite(slice.Index(i), elem); err != nil {
+			return true, fmt.Errorf("element %d: %w", i, err)
+		}
+	}
+	dest.Set(slice)
+	return true, nil
+}
+
+// structType returns the struct type of the struct or pointer to struct
+func structType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	return t
+}
+
+// setComposite sets fields of the composite value to exported fields of
+// the struct or the pointer to struct allocated if nil
+func setComposite(dest reflect.Value, fields []any) error {
+	if dest.Kind() == reflect.Pointer {
+		if fields == nil {
+			dest.Set(reflect.Zero(dest.Type()))
+			return nil
+		}
+		dest.Set(reflect.New(dest.Type().Elem()))
+		dest = dest.Elem()
+	}
+	var exported []int
+	for i := 0; i < dest.NumField(); i++ {
+		if dest.Type().Field(i).IsExported() {
+			exported = append(exported, i)
+		}
+	}
+	if len(fields) != len(exported) {
+		return fmt.Errorf("composite has %d fields, but %s has %d exported fields", len(fields), dest.Type(), len(exported))
+	}
+	for i, v := range fields {
+		field := dest.Field(exported[i])
+		if v == nil {
+			field.Set(reflect.Zero(field.Type()))
+			continue
+		}
+		if val := reflect.ValueOf(v); val.Type().AssignableTo(field.Type()) {
+			field.Set(val)
+			continue
+		}
+		if ok, err := scanComposite(field, v); ok {
+			if err != nil {
+				return err
 			}
+			continue
+		}
+		return fmt.Errorf("cannot set %T to field %s of %s", v, dest.Type().Field(exported[i]).Name, dest.Type())
+	}
+	return nil
+}
 
+// scanNullValue scans NULL of the i-th column using Scanner interfaces
+// of the destination if any, otherwise see scanNull
+func (rs *rowSets) scanNullValue(i int, col pgconn.FieldDescription, dest any) error {
+	if ok, err := scanCustom(dest, nil); ok {
+		if err != nil {
+			return fmt.Errorf("Scanning value error for column '%s': %w", col.Name, err)
 		}
+		return nil
 	}
-	return r.nextErr[r.recNo-1]
+	if err := rs.scanNull(reflect.ValueOf(dest).Elem()); err != nil {
+		return fmt.Errorf("can't scan into dest[%d]: %w", i, err)
+	}
+	return nil
 }
 
+// scanNull sets NULL to the destination if the type may hold it,
+// otherwise it is done according to the NullScanMode option
+func (rs *rowSets) scanNull(dest reflect.Value) error {
+	switch dest.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map:
+	default:
+		if rs.ex == nil || rs.ex.mock == nil || rs.ex.mock.nullScanMode != NullScanZero {
+			return fmt.Errorf("cannot scan NULL into %s", dest.Type())
+		}
+	}
+	dest.Set(reflect.Zero(dest.Type()))
+	return nil
+}
+
+// scanTypeMap converts the value to the destination using the type registered
+// in the type map of the mock for the column type OID, the same way pgx does
+func (rs *rowSets) scanTypeMap(oid uint32, src, dest any) (bool, error) {
+	if oid == 0 || rs.ex == nil || rs.ex.mock == nil || rs.ex.mock.typeMap == nil {
+		return false, nil
+	}
+	m := rs.ex.mock.typeMap
+	if _, ok := m.TypeForOID(oid); !ok {
+		return false, nil
+	}
+	buf, err := m.Encode(oid, pgtype.TextFormatCode, src, nil)
+	if err != nil {
+		return true, err
+	}
+	return true, m.Scan(oid, pgtype.TextFormatCode, buf, dest)
+}
+
+// RawValues returns nil if there is no current row, the same as pgx does
 func (rs *rowSets) RawValues() [][]byte {
 	r := rs.sets[rs.RowSetNo]
-	dest := make([][]byte, len(r.defs))
+	if r.recNo < 1 || r.recNo > len(r.rows) {
+		return nil
+	}
+	dest := make([][]byte, len(r.rows[r.recNo-1]))
 
 	for i, col := range r.rows[r.recNo-1] {
 		if b, ok := rawBytes(col); ok {
@@ -185,6 +468,11 @@ type Rows struct {
 	recNo      int
 	nextErr    map[int]error
 	closeErr   error
+	transform  func(col int, v any) any
+	// rows after the first throttleAfter ones are delayed by throttleDelay
+	throttleAfter int
+	throttleDelay time.Duration
+	fetchSize     int // rows returned by every FETCH, see AsCursor
 }
 
 // NewRows allows Rows to be created from a
@@ -203,8 +491,8 @@ func NewRows(columns []string) *Rows {
 }
 
 // CloseError allows to set an error
-// which will be returned by rows.Close
-// function.
+// which will be returned by rows.Err()
+// after rows.Close() function.
 //
 // The close error will be triggered only in cases
 // when rows.Next() EOF was not yet reached, that is
@@ -214,6 +502,47 @@ func (r *Rows) CloseError(err error) *Rows {
 	return r
 }
 
+// AsCursor allows to return rows in chunks of fetchSize rows by subsequent
+// queries, e.g. FETCH of the server-side cursor, each with "FETCH n" command
+// tag. The expectation awaits len(rows)/fetchSize+1 calls, until the chunk
+// shorter than fetchSize, possibly empty, marks the end of the cursor.
+// Rows may be added before or after the rows are passed to WillReturnRows.
+// The number of calls is defined by the cursor, so the expectation with
+// cursor rows must not be limited by Times(), such queries fail to match.
+func (r *Rows) AsCursor(fetchSize int) *Rows {
+	r.fetchSize = fetchSize
+	return r
+}
+
+// fetches returns the number of FETCH calls to read all cursor rows
+func (r *Rows) fetches() int {
+	return len(r.rows)/r.fetchSize + 1
+}
+
+// fetch returns the i-th chunk of cursor rows
+func (r *Rows) fetch(i int) *Rows {
+	from := min(i*r.fetchSize, len(r.rows))
+	to := min(from+r.fetchSize, len(r.rows))
+	chunk := *r
+	chunk.rows, chunk.recNo, chunk.fetchSize = r.rows[from:to], 0, 0
+	chunk.commandTag = NewResult("FETCH", int64(to-from))
+	chunk.nextErr = make(map[int]error)
+	for row, err := range r.nextErr {
+		if row >= from && row < to {
+			chunk.nextErr[row-from] = err
+		}
+	}
+	return &chunk
+}
+
+// DelayAfterRow allows to return the first n rows without delay and to delay
+// every next row for the duration, simulating a server which buffers, then
+// throttles. The delay overrides the one set by WillDelayPerRow for such rows.
+func (r *Rows) DelayAfterRow(n int, d time.Duration) *Rows {
+	r.throttleAfter, r.throttleDelay = n, d
+	return r
+}
+
 // RowError allows to set an error
 // which will be returned when a given
 // row number is read
@@ -237,6 +566,16 @@ func (r *Rows) AddRow(values ...any) *Rows {
 	return r
 }
 
+// AddMalformedRow adds a row without checking that the number of values
+// matches the number of columns. Useful to simulate a driver bug and to test
+// how scanners handle malformed input. Scan() returns an error for such row.
+func (r *Rows) AddMalformedRow(values ...any) *Rows {
+	row := make([]interface{}, len(values))
+	copy(row, values)
+	r.rows = append(r.rows, row)
+	return r
+}
+
 // AddRows adds multiple rows composed from any slice and
 // returns the same instance to perform subsequent actions.
 func (r *Rows) AddRows(values ...[]any) *Rows {
@@ -246,6 +585,33 @@ func (r *Rows) AddRows(values ...[]any) *Rows {
 	return r
 }
 
+// AddRowsN adds n rows, values of each row are returned by fn called
+// with the row index starting from 0. Returns the same instance to perform
+// subsequent actions. Useful to build large result sets, e.g. for pagination.
+func (r *Rows) AddRowsN(n int, fn func(i int) []any) *Rows {
+	r.rows = slices.Grow(r.rows, n)
+	for i := 0; i < n; i++ {
+		r.AddRow(fn(i)...)
+	}
+	return r
+}
+
+// WithValueTransformer allows to transform every value before it is
+// returned by Scan() or Values(), e.g. to simulate a driver returning
+// numbers as strings. Columns are numbered from 0.
+func (r *Rows) WithValueTransformer(fn func(col int, v any) any) *Rows {
+	r.transform = fn
+	return r
+}
+
+// value returns the value of the column after the transformation if any
+func (r *Rows) value(col int, v any) any {
+	if r.transform == nil {
+		return v
+	}
+	return r.transform(col, v)
+}
+
 // AddCommandTag will add a command tag to the result set
 func (r *Rows) AddCommandTag(tag pgconn.CommandTag) *Rows {
 	r.commandTag = tag
@@ -255,7 +621,10 @@ func (r *Rows) AddCommandTag(tag pgconn.CommandTag) *Rows {
 // FromCSVString build rows from csv string.
 // return the same instance to perform subsequent actions.
 // Note that the number of values must match the number
-// of columns
+// of columns. Values of columns with a DataTypeOID known to
+// the default pgtype.Map are parsed as the text representation
+// of the type, e.g. int4 values become int32. Panics if a value
+// can't be parsed.
 func (r *Rows) FromCSVString(s string) *Rows {
 	res := strings.NewReader(strings.TrimSpace(s))
 	csvReader := csv.NewReader(res)
@@ -269,12 +638,30 @@ func (r *Rows) FromCSVString(s string) *Rows {
 		row := make([]interface{}, len(r.defs))
 		for i, v := range res {
 			row[i] = CSVColumnParser(strings.TrimSpace(v))
+			if s, ok := row[i].(string); ok && i < len(r.defs) && r.defs[i].DataTypeOID != 0 {
+				row[i] = parseCSVValue(r.defs[i], s)
+			}
 		}
 		r.rows = append(r.rows, row)
 	}
 	return r
 }
 
+// parseCSVValue decodes the text representation of the column type,
+// values of types unknown to the default type map are kept as strings
+func parseCSVValue(col pgconn.FieldDescription, s string) interface{} {
+	m := pgtype.NewMap()
+	t, ok := m.TypeForOID(col.DataTypeOID)
+	if !ok {
+		return s
+	}
+	v, err := t.Codec.DecodeValue(m, col.DataTypeOID, pgtype.TextFormatCode, []byte(s))
+	if err != nil {
+		panic(fmt.Sprintf("pgxmock: can't parse csv value '%s' of column %s as %s: %s", s, col.Name, t.Name, err))
+	}
+	return v
+}
+
 // Kind returns rows corresponding to the interface pgx.Rows
 // useful for testing entities that implement an interface pgx.RowScanner
 func (r *Rows) Kind() pgx.Rows {
@@ -290,3 +677,48 @@ func NewRowsWithColumnDefinition(columns ...pgconn.FieldDescription) *Rows {
 		nextErr: make(map[int]error),
 	}
 }
+
+// NewRowsForStruct returns rows with columns of the struct fields, named the
+// same way as pgx.RowToStructByName does, i.e. by the "db" tag or the field
+// name. Type OIDs are inferred from field types by the default pgtype.Map.
+// Fields of embedded structs are included, unexported and "-" tagged ones
+// are skipped. Panics if T is not a struct.
+func NewRowsForStruct[T any]() *Rows {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("pgxmock: NewRowsForStruct type %s is not a struct", t))
+	}
+	return NewRowsWithColumnDefinition(structColumns(t, pgtype.NewMap())...)
+}
+
+// structColumns returns column definitions of the struct fields
+func structColumns(t reflect.Type, m *pgtype.Map) (columns []pgconn.FieldDescription) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue // unexported
+		}
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			columns = append(columns, structColumns(sf.Type, m)...)
+			continue
+		}
+		name, tagged := sf.Tag.Lookup("db")
+		name, _, _ = strings.Cut(name, ",")
+		if name == "-" {
+			continue
+		}
+		if !tagged {
+			name = sf.Name
+		}
+		column := pgconn.FieldDescription{Name: name}
+		ft := sf.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if typ, ok := m.TypeForValue(reflect.New(ft).Elem().Interface()); ok {
+			column.DataTypeOID = typ.OID
+		}
+		columns = append(columns, column)
+	}
+	return columns
+}
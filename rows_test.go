@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -227,7 +229,7 @@ func ExampleRows_expectToBeClosed() {
 	}
 
 	/*Output: got error: expected query rows to be closed, but it was not: ExpectedQuery => expecting call to Query() or to QueryRow():
-	- matches sql: 'SELECT'
+	- matches sql (regexp): 'SELECT'
 	- is without arguments
 	- returns data:
 		result set: 0
@@ -407,6 +409,41 @@ func ExampleRows_values() {
 	// [] <nil>
 }
 
+func TestRowsValues(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+
+	now := time.Now()
+	values := []any{int64(42), "John", nil, now, []string{"a", "b"}, pgtype.Text{String: "foo", Valid: true}}
+	rows := NewRowsWithColumnDefinition(
+		pgconn.FieldDescription{Name: "id", DataTypeOID: pgtype.Int8OID},
+		pgconn.FieldDescription{Name: "name", DataTypeOID: pgtype.TextOID},
+		pgconn.FieldDescription{Name: "email", DataTypeOID: pgtype.TextOID},
+		pgconn.FieldDescription{Name: "created", DataTypeOID: pgtype.TimestamptzOID},
+		pgconn.FieldDescription{Name: "tags", DataTypeOID: pgtype.TextArrayOID},
+		pgconn.FieldDescription{Name: "note", DataTypeOID: pgtype.TextOID},
+	).AddRow(values...)
+	mock.ExpectQuery("SELECT").WillReturnRows(rows)
+
+	rs, err := mock.Query(ctx, "SELECT")
+	a.NoError(err)
+	defer rs.Close()
+	_, err = rs.Values()
+	a.Error(err, "Values() must not be called before Next()")
+	a.True(rs.Next())
+	v, err := rs.Values()
+	a.NoError(err)
+	a.Equal(values, v, "values must be returned with the same types and shape")
+	for i := range values {
+		a.IsType(values[i], v[i])
+	}
+	v[0] = "changed"
+	v, _ = rs.Values()
+	a.Equal(int64(42), v[0], "returned slice must not alias mocked row")
+	a.False(rs.Next())
+}
+
 func ExampleRows_rawValues() {
 	mock, err := NewConn()
 	if err != nil {
@@ -551,6 +588,40 @@ func TestRowsScanErrorOnScannerIface(t *testing.T) {
 	}
 }
 
+// upperText implements only pgtype.TextScanner
+type upperText string
+
+func (u *upperText) ScanText(v pgtype.Text) error {
+	*u = upperText(strings.ToUpper(v.String))
+	return nil
+}
+
+func TestRowsScanWithCustomScanners(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+	now := time.Now()
+	rows := NewRows([]string{"name", "id", "created", "deleted", "score"}).
+		AddRow("john", int64(42), now, nil, nil)
+	mock.ExpectQuery("SELECT").WillReturnRows(rows)
+
+	var (
+		name    upperText
+		id      testScanner
+		created pgtype.Timestamptz
+		deleted = pgtype.Timestamptz{Valid: true}
+		score   = pgtype.Float8{Float64: 1, Valid: true}
+	)
+	a.NoError(mock.QueryRow(context.Background(), "SELECT").Scan(&name, &id, &created, &deleted, &score))
+	a.Equal(upperText("JOHN"), name, "pgtype.TextScanner expected to be called")
+	a.EqualValues(42, id.Value, "sql.Scanner expected to be called")
+	a.True(created.Valid)
+	a.True(now.Equal(created.Time))
+	a.False(deleted.Valid, "NULL expected to be passed to scanner")
+	a.False(score.Valid, "NULL expected to be passed to scanner")
+	a.NoError(mock.ExpectationsWereMet())
+}
+
 func TestCSVRowParser(t *testing.T) {
 	t.Parallel()
 	rs := NewRows([]string{"col1", "col2"}).FromCSVString("a,NULL")
@@ -582,6 +653,67 @@ func TestCSVRowParser(t *testing.T) {
 	}
 }
 
+func TestWillReturnRowsCSV(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+
+	mock.ExpectQuery("SELECT").WillReturnRowsCSV([]string{"id", "name"}, `
+		1,Alice
+		2,NULL`)
+
+	rows, err := mock.Query(context.Background(), "SELECT id, name FROM users")
+	a.NoError(err)
+	defer rows.Close()
+	var ids []string
+	var names []any
+	for rows.Next() {
+		values, err := rows.Values()
+		a.NoError(err)
+		ids = append(ids, values[0].(string))
+		names = append(names, values[1])
+	}
+	a.Equal([]string{"1", "2"}, ids)
+	a.Equal([]any{"Alice", nil}, names)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestWillReturnRowsCSVWithColumnDefinition(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+
+	columns := []pgconn.FieldDescription{
+		{Name: "id", DataTypeOID: pgtype.Int4OID},
+		{Name: "name", DataTypeOID: pgtype.TextOID},
+		{Name: "active", DataTypeOID: pgtype.BoolOID},
+		{Name: "created", DataTypeOID: pgtype.DateOID},
+		{Name: "note"},
+		{Name: "custom", DataTypeOID: 100001},
+	}
+	mock.ExpectQuery("SELECT").WillReturnRowsCSVWithColumnDefinition(columns, `
+		1,Alice,true,2024-01-31,42,x
+		2,NULL,f,NULL,NULL,y`)
+
+	rows, err := mock.Query(ctx, "SELECT * FROM users")
+	a.NoError(err)
+	var values [][]any
+	for rows.Next() {
+		v, err := rows.Values()
+		a.NoError(err)
+		values = append(values, v)
+	}
+	a.Equal([][]any{
+		{int32(1), "Alice", true, time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), "42", "x"},
+		{int32(2), nil, false, nil, nil, "y"},
+	}, values)
+	a.NoError(mock.ExpectationsWereMet())
+
+	a.PanicsWithValue(`pgxmock: can't parse csv value 'one' of column id as int4: strconv.ParseInt: parsing "one": invalid syntax`, func() {
+		NewRowsWithColumnDefinition(columns[0]).FromCSVString("one")
+	})
+}
+
 func TestWrongNumberOfValues(t *testing.T) {
 	// Open new mock database
 	mock, err := NewConn()
@@ -599,6 +731,157 @@ func TestWrongNumberOfValues(t *testing.T) {
 	t.Error("expected panic from query")
 }
 
+func TestMalformedRow(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+
+	rows := NewRows([]string{"id", "name"}).
+		AddMalformedRow(1).
+		AddMalformedRow(2, "John", "unexpected")
+	mock.ExpectQuery("SELECT").WillReturnRows(rows)
+
+	rs, err := mock.Query(ctx, "SELECT id, name FROM users")
+	a.NoError(err)
+	defer rs.Close()
+	var (
+		id   int
+		name string
+	)
+	for rs.Next() {
+		a.Len(rs.FieldDescriptions(), 2)
+		a.Error(rs.Scan(&id, &name), "malformed row should not be scanned")
+		values, err := rs.Values()
+		a.NoError(err)
+		a.NotEqual(len(rs.FieldDescriptions()), len(values))
+		a.Len(rs.RawValues(), len(values))
+	}
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestColumnsOrder(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+
+	columns := []string{"z", "a", "m", "b", "y", "c"}
+	rows := NewRows(columns).AddRow(1, 2, 3, 4, 5, 6)
+	defRows := NewRowsWithColumnDefinition(
+		pgconn.FieldDescription{Name: "last", DataTypeOID: pgtype.TextOID},
+		pgconn.FieldDescription{Name: "first", DataTypeOID: pgtype.Int4OID},
+	).AddRow("foo", 1)
+	mock.ExpectQuery("SELECT").WillReturnRows(rows)
+	mock.ExpectQuery("SELECT").WillReturnRows(defRows)
+
+	names := func(rs pgx.Rows) (res []string) {
+		for _, fd := range rs.FieldDescriptions() {
+			res = append(res, fd.Name)
+		}
+		return
+	}
+
+	rs, err := mock.Query(ctx, "SELECT")
+	a.NoError(err)
+	a.Equal(columns, names(rs), "columns must be in the NewRows() order")
+	a.True(rs.Next())
+	var v [6]int
+	a.NoError(rs.Scan(&v[0], &v[1], &v[2], &v[3], &v[4], &v[5]))
+	a.Equal([6]int{1, 2, 3, 4, 5, 6}, v, "values must be scanned in the columns order")
+	rs.Close()
+
+	rs, err = mock.Query(ctx, "SELECT")
+	a.NoError(err)
+	a.Equal([]string{"last", "first"}, names(rs))
+	a.Equal(uint32(pgtype.TextOID), rs.FieldDescriptions()[0].DataTypeOID)
+	rs.Close()
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestWillDelayPerRow(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+
+	ex := mock.ExpectQuery("SELECT").
+		WillReturnRows(NewRows([]string{"id"}).AddRows([]any{1}, []any{2}, []any{3})).
+		WillDelayPerRow(20 * time.Millisecond)
+	a.Contains(ex.String(), "delayed every row for: 20ms")
+
+	c, cancel := context.WithTimeout(ctx, 30*time.Millisecond)
+	defer cancel()
+	rs, err := mock.Query(c, "SELECT id FROM foo")
+	a.NoError(err, "only rows are delayed, not the initial response")
+	start := time.Now()
+	a.True(rs.Next())
+	a.GreaterOrEqual(time.Since(start), 20*time.Millisecond)
+	a.False(rs.Next(), "context is done before the second row")
+	a.ErrorIs(rs.Err(), context.DeadlineExceeded)
+	rs.Close()
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestDelayAfterRow(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+
+	mock.ExpectQuery("SELECT").WillReturnRows(NewRows([]string{"id"}).
+		AddRows([]any{1}, []any{2}, []any{3}, []any{4}).
+		DelayAfterRow(2, 20*time.Millisecond))
+	rs, err := mock.Query(ctx, "SELECT id FROM foo")
+	a.NoError(err)
+	start := time.Now()
+	a.True(rs.Next())
+	a.True(rs.Next())
+	a.Less(time.Since(start), 20*time.Millisecond, "buffered rows must not be delayed")
+	for i := 0; i < 2; i++ {
+		start = time.Now()
+		a.True(rs.Next())
+		a.GreaterOrEqual(time.Since(start), 20*time.Millisecond)
+	}
+	a.False(rs.Next())
+	rs.Close()
+	a.NoError(rs.Err())
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestRowsContextCancelled(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+
+	mock.ExpectQuery("SELECT").
+		WillReturnRows(NewRows([]string{"id"}).AddRows([]any{1}, []any{2}, []any{3}))
+	c, cancel := context.WithCancel(ctx)
+	rs, err := mock.Query(c, "SELECT id FROM foo")
+	a.NoError(err)
+	a.True(rs.Next())
+	a.NoError(rs.Err())
+	cancel()
+	a.False(rs.Next(), "rows must stop once the context is cancelled")
+	a.ErrorIs(rs.Err(), context.Canceled)
+	rs.Close()
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestZeroColumnRows(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+
+	mock.ExpectQuery("SET search_path").WillReturnRows()
+	mock.ExpectQuery("SET search_path").WillReturnRows(NewRows([]string{}))
+	for i := 0; i < 2; i++ {
+		rs, err := mock.Query(ctx, "SET search_path TO tenant")
+		a.NoError(err)
+		a.Empty(rs.FieldDescriptions())
+		a.False(rs.Next(), "zero column rows must iterate zero times")
+		a.NoError(rs.Err())
+		rs.Close()
+	}
+	a.NoError(mock.ExpectationsWereMet())
+}
+
 func TestEmptyRowSets(t *testing.T) {
 	rs1 := NewRows([]string{"a"}).AddRow("a")
 	rs2 := NewRows([]string{"b"})
@@ -700,3 +983,342 @@ func TestRowsKind(t *testing.T) {
 		}
 	}
 }
+
+func TestRowsSelectCommandTag(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+
+	mock.ExpectQuery("SELECT").WillReturnRows(NewRows([]string{"id"}))
+	rs, err := mock.Query(ctx, "SELECT id FROM users")
+	a.NoError(err)
+	a.Equal("SELECT 0", rs.CommandTag().String())
+	a.True(rs.CommandTag().Select())
+	rs.Close()
+
+	mock.ExpectQuery("SELECT").WillReturnRows(NewRows([]string{"id"}).AddRow(1).AddRow(2).AddRow(3))
+	rs, err = mock.Query(ctx, "SELECT id FROM users")
+	a.NoError(err)
+	a.EqualValues(3, rs.CommandTag().RowsAffected())
+	rs.Close()
+
+	mock.ExpectQuery("SELECT").WillReturnRows(NewRows([]string{"id"}).AddRow(1).
+		AddCommandTag(pgconn.NewCommandTag("FETCH 1")))
+	rs, err = mock.Query(ctx, "SELECT id FROM users")
+	a.NoError(err)
+	a.Equal("FETCH 1", rs.CommandTag().String(), "tag set by AddCommandTag is kept")
+	rs.Close()
+
+	// results without columns keep the empty tag returned before
+	mock.ExpectQuery("INSERT").WillReturnRows(NewRows(nil))
+	rs, err = mock.Query(ctx, "INSERT INTO users DEFAULT VALUES")
+	a.NoError(err)
+	a.Empty(rs.CommandTag().String(), "no tag is synthesized for results without columns")
+	rs.Close()
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestAddRowsN(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+	rows := NewRows([]string{"id", "name"}).
+		AddRow(0, "header").
+		AddRowsN(10000, func(i int) []any { return []any{i + 1, "user"} })
+	mock.ExpectQuery("SELECT").WillReturnRows(rows)
+
+	rs, err := mock.Query(context.Background(), "SELECT")
+	a.NoError(err)
+	defer rs.Close()
+	var count, id int
+	var name string
+	for rs.Next() {
+		a.NoError(rs.Scan(&id, &name))
+		a.Equal(count, id)
+		count++
+	}
+	a.Equal(10001, count)
+	a.Equal("user", name)
+	a.Panics(func() { NewRows([]string{"id"}).AddRowsN(1, func(int) []any { return nil }) })
+}
+
+func TestWithValueTransformer(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+	rows := NewRows([]string{"id", "name"}).
+		AddRow(42, "john").
+		AddRow(nil, "mary").
+		WithValueTransformer(func(col int, v any) any {
+			if col == 0 && v != nil {
+				return fmt.Sprint(v)
+			}
+			return v
+		})
+	mock.ExpectQuery("SELECT").WillReturnRows(rows)
+
+	rs, err := mock.Query(context.Background(), "SELECT")
+	a.NoError(err)
+	defer rs.Close()
+	a.True(rs.Next())
+	var id, name string
+	a.NoError(rs.Scan(&id, &name))
+	a.Equal("42", id, "numbers expected to be returned as strings")
+	values, err := rs.Values()
+	a.NoError(err)
+	a.Equal([]any{"42", "john"}, values)
+	a.True(rs.Next())
+	values, err = rs.Values()
+	a.NoError(err)
+	a.Equal([]any{nil, "mary"}, values)
+}
+
+func TestRowsAccessors(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+	errClose := errors.New("close failed")
+
+	mock.ExpectQuery("SELECT").WillReturnRows(NewRows([]string{"id", "name"}).
+		AddRow(1, "John").AddRow(2, "Jane").CloseError(errClose))
+	rs, err := mock.Query(ctx, "SELECT id, name FROM users")
+	a.NoError(err)
+	a.Nil(rs.Conn())
+	a.Len(rs.FieldDescriptions(), 2)
+	a.Equal("SELECT 2", rs.CommandTag().String())
+	a.NoError(rs.Err())
+	a.Nil(rs.RawValues(), "no raw values before Next()")
+	a.ErrorIs(rs.Scan(new(int), new(string)), errNoRow)
+	_, err = rs.Values()
+	a.ErrorIs(err, errNoRow)
+
+	a.True(rs.Next())
+	a.Len(rs.RawValues(), 2)
+	values, err := rs.Values()
+	a.NoError(err)
+	a.Equal([]any{1, "John"}, values)
+	rs.Close()
+	a.ErrorIs(rs.Err(), errClose, "close error must be returned if not all rows were read")
+
+	mock.ExpectQuery("SELECT").WillReturnRows(NewRows([]string{"id"}).AddRow(1).
+		AddCommandTag(pgconn.NewCommandTag("FETCH 1")))
+	rs, err = mock.Query(ctx, "SELECT id FROM users")
+	a.NoError(err)
+	a.Equal("FETCH 1", rs.CommandTag().String())
+	for rs.Next() {
+	}
+	rs.Close()
+	a.NoError(rs.Err())
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestScanWithTypeMap(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	mock, err := NewConn()
+	a.NoError(err)
+	a.NotNil(mock.TypeMap())
+	// register a custom type the same way it is done for the real connection
+	const myIntOID = 100001
+	mock.TypeMap().RegisterType(&pgtype.Type{Name: "myint", OID: myIntOID, Codec: pgtype.Int4Codec{}})
+
+	rows := NewRowsWithColumnDefinition(
+		pgconn.FieldDescription{Name: "id", DataTypeOID: pgtype.Int4OID},
+		pgconn.FieldDescription{Name: "custom", DataTypeOID: myIntOID},
+		pgconn.FieldDescription{Name: "name"},
+	).AddRow(int64(42), int64(7), 1)
+	mock.ExpectQuery("SELECT").WillReturnRows(rows).Times(2)
+
+	var id string
+	var custom int32
+	var name string
+	a.NoError(mock.QueryRow(ctx, "SELECT").Scan(&id, &custom, new(any)))
+	a.Equal("42", id)
+	a.EqualValues(7, custom)
+	a.Error(mock.QueryRow(ctx, "SELECT").Scan(&id, &custom, &name), "no type for the column OID")
+	a.NoError(mock.ExpectationsWereMet())
+
+	mock, err = NewConn(TypeMapOption(func(m *pgtype.Map) {
+		m.RegisterType(&pgtype.Type{Name: "myint", OID: myIntOID, Codec: pgtype.Int4Codec{}})
+	}))
+	a.NoError(err)
+	clone, _ := mock.Clone()
+	a.NotSame(mock.TypeMap(), clone.TypeMap(), "clones must not share the type map")
+	_, ok := clone.TypeMap().TypeForOID(myIntOID)
+	a.True(ok, "types of TypeMapOption must be registered for clones")
+	const otherOID = 100002
+	mock.TypeMap().RegisterType(&pgtype.Type{Name: "other", OID: otherOID, Codec: pgtype.Int4Codec{}})
+	_, ok = clone.TypeMap().TypeForOID(otherOID)
+	a.False(ok, "types registered in one mock must not appear in clones")
+}
+
+func TestNullScanMode(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	rows := func() *Rows { return NewRows([]string{"id", "name", "note"}).AddRow(nil, nil, nil) }
+	name := "John"
+	note := &name
+
+	mock, _ := NewConn()
+	mock.ExpectQuery("SELECT").WillReturnRows(rows())
+	var id int
+	err := mock.QueryRow(ctx, "SELECT").Scan(&id, &name, &note)
+	a.EqualError(err, "can't scan into dest[0]: cannot scan NULL into int")
+
+	mock, _ = NewConn(NullScanMode(NullScanZero))
+	id = 42
+	mock.ExpectQuery("SELECT").WillReturnRows(rows())
+	a.NoError(mock.QueryRow(ctx, "SELECT").Scan(&id, &name, &note))
+	a.Zero(id)
+	a.Zero(name)
+	a.Nil(note, "pointers must get NULL in any mode")
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestRowsAsCursor(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	mock, _ := NewConn()
+	rows := NewRows([]string{"id"}).AsCursor(2)
+	for i := 1; i <= 5; i++ {
+		rows.AddRow(i)
+	}
+	mock.ExpectExec("DECLARE c CURSOR").WillReturnResult(NewResult("DECLARE CURSOR", 0))
+	e := mock.ExpectQuery("FETCH 2 FROM c").WillReturnRows(rows)
+	a.Contains(e.String(), "returns rows as a cursor in 3 fetches")
+
+	_, err := mock.Exec(ctx, "DECLARE c CURSOR FOR SELECT id FROM items")
+	a.NoError(err)
+	var ids []int
+	fetches := 0
+	for {
+		rs, err := mock.Query(ctx, "FETCH 2 FROM c")
+		a.NoError(err)
+		fetches++
+		n := 0
+		for rs.Next() {
+			var id int
+			a.NoError(rs.Scan(&id))
+			ids = append(ids, id)
+			n++
+		}
+		a.Equal(fmt.Sprintf("FETCH %d", n), rs.CommandTag().String())
+		rs.Close()
+		if n < 2 {
+			break
+		}
+	}
+	a.Equal([]int{1, 2, 3, 4, 5}, ids)
+	a.Equal(3, fetches)
+	a.NoError(mock.ExpectationsWereMet())
+	_, err = mock.Query(ctx, "FETCH 2 FROM c")
+	a.Error(err, "cursor is exhausted")
+
+	mock.ExpectQuery("FETCH").WillReturnRows(NewRows([]string{"id"}).AddRows([]any{1}, []any{2}).AsCursor(2))
+	rs, err := mock.Query(ctx, "FETCH 2 FROM c")
+	a.NoError(err)
+	rs.Close()
+	a.Error(mock.ExpectationsWereMet(), "the final empty fetch is expected")
+
+	mock, _ = NewConn()
+	rows = NewRows([]string{"id"})
+	mock.ExpectQuery("FETCH").WillReturnRows(rows)
+	rows.AddRow(1).AddRow(2).AddRow(3).AsCursor(2)
+	for _, n := range []int{2, 1} {
+		rs, err := mock.Query(ctx, "FETCH 2 FROM c")
+		a.NoError(err)
+		for rs.Next() {
+		}
+		a.Equal(fmt.Sprintf("FETCH %d", n), rs.CommandTag().String(), "AsCursor may be called after WillReturnRows")
+		rs.Close()
+	}
+	a.NoError(mock.ExpectationsWereMet())
+
+	mock, _ = NewConn()
+	mock.ExpectQuery("FETCH").WillReturnRows(NewRows([]string{"id"}).AddRow(1).AsCursor(2)).Times(3)
+	_, err = mock.Query(ctx, "FETCH 2 FROM c")
+	a.ErrorContains(err, "Times(3) can't be used for rows returned by AsCursor() in 1 fetches")
+}
+
+type structAudit struct {
+	UpdatedBy string `db:"updated_by"`
+}
+
+type structUser struct {
+	ID      int64          `db:"id"`
+	Name    string         // no tag
+	Email   *string        `db:"email,omitempty"`
+	Created time.Time      `db:"created_at"`
+	Ignored int            `db:"-"`
+	secret  string         //nolint:unused
+	Balance pgtype.Numeric `db:"balance"`
+	structAudit
+}
+
+func TestNewRowsForStruct(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	rows := NewRowsForStruct[structUser]()
+	var names []string
+	var oids []uint32
+	for _, d := range rows.defs {
+		names = append(names, d.Name)
+		oids = append(oids, d.DataTypeOID)
+	}
+	a.Equal([]string{"id", "Name", "email", "created_at", "balance", "updated_by"}, names)
+	a.Equal([]uint32{pgtype.Int8OID, pgtype.TextOID, pgtype.TextOID, pgtype.TimestamptzOID, pgtype.NumericOID, pgtype.TextOID}, oids)
+	a.Panics(func() { NewRowsForStruct[int]() })
+
+	email := "john@example.com"
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	rows.AddRow(int64(1), "John", &email, created, pgtype.Numeric{}, "admin")
+	mock, _ := NewConn()
+	mock.ExpectQuery("SELECT").WillReturnRows(rows)
+	rs, err := mock.Query(ctx, "SELECT * FROM users")
+	a.NoError(err)
+	users, err := pgx.CollectRows(rs, pgx.RowToStructByName[structUser])
+	a.NoError(err)
+	a.Equal([]structUser{{ID: 1, Name: "John", Email: &email, Created: created, structAudit: structAudit{UpdatedBy: "admin"}}}, users)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+type compositeItem struct {
+	SKU      string
+	Quantity int
+	Tags     []string
+	note     string //nolint:unused
+}
+
+type compositeAddress struct {
+	City  string
+	Items []compositeItem
+}
+
+func TestScanCompositeArrays(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	mock, _ := NewConn()
+	items := NewRows([]string{"sku", "quantity", "tags"}).
+		AddRow("A-1", 2, []string{"new"}).
+		AddRow("B-2", 1, nil)
+	mock.ExpectQuery("SELECT").WillReturnRows(NewRows([]string{"id", "items", "ptrs", "address"}).
+		AddRow(1, [][]any{{"A-1", 2, []string{"new"}}, {"B-2", 1, nil}}, items, []any{"Paris", [][]any{{"C-3", 5, nil}}}))
+
+	var id int
+	var values []compositeItem
+	var ptrs []*compositeItem
+	var address compositeAddress
+	a.NoError(mock.QueryRow(ctx, "SELECT").Scan(&id, &values, &ptrs, &address))
+	expected := []compositeItem{{SKU: "A-1", Quantity: 2, Tags: []string{"new"}}, {SKU: "B-2", Quantity: 1}}
+	a.Equal(expected, values)
+	a.Equal([]*compositeItem{&expected[0], &expected[1]}, ptrs)
+	a.Equal(compositeAddress{City: "Paris", Items: []compositeItem{{SKU: "C-3", Quantity: 5}}}, address)
+
+	mock.ExpectQuery("SELECT").WillReturnRows(NewRows([]string{"items"}).AddRow([][]any{{"A-1", "two", nil}}))
+	err := mock.QueryRow(ctx, "SELECT").Scan(&values)
+	a.ErrorContains(err, "element 0: cannot set string to field Quantity of pgxmock.compositeItem")
+	mock.ExpectQuery("SELECT").WillReturnRows(NewRows([]string{"items"}).AddRow([][]any{{"A-1"}}))
+	err = mock.QueryRow(ctx, "SELECT").Scan(&values)
+	a.ErrorContains(err, "composite has 1 fields, but pgxmock.compositeItem has 3 exported fields")
+	a.NoError(mock.ExpectationsWereMet())
+}
@@ -0,0 +1,79 @@
+package pgxmock
+
+import (
+	"fmt"
+
+	pgconn "github.com/jackc/pgx/v5/pgconn"
+)
+
+// ExpectationSpec declares an expectation loaded by LoadExpectations,
+// so expectations may be generated from test tables
+type ExpectationSpec struct {
+	Method   string   // Query, Exec, Begin, Commit, Rollback or Ping
+	SQL      string   // expected SQL of Query or Exec
+	Args     []any    // expected arguments of Query or Exec, nil means no arguments
+	Columns  []string // columns of rows returned by Query
+	Rows     [][]any  // rows returned by Query
+	Result   string   // command tag returned by Exec, e.g. "UPDATE 1"
+	Error    error    // error returned instead of the result
+	Times    uint     // number of expected calls, zero means one
+	Optional bool     // the call may not happen
+	Label    string   // human-readable name used in failure messages
+}
+
+// apply sets common modifiers of the spec
+func (s ExpectationSpec) apply(e CallModifier) {
+	if s.Times > 0 {
+		e.Times(s.Times)
+	}
+	if s.Optional {
+		e.Maybe()
+	}
+	if s.Label != "" {
+		e.Label(s.Label)
+	}
+	if s.Error != nil {
+		e.WillReturnError(s.Error)
+	}
+}
+
+func (c *pgxmock) LoadExpectations(specs []ExpectationSpec) error {
+	for i, s := range specs {
+		switch s.Method {
+		case "Query":
+			for j, row := range s.Rows {
+				if len(row) != len(s.Columns) {
+					return fmt.Errorf("row #%d of expectation spec #%d has %d values for %d columns", j, i, len(row), len(s.Columns))
+				}
+			}
+			e := c.ExpectQuery(s.SQL)
+			if s.Args != nil {
+				e.WithArgs(s.Args...)
+			}
+			if s.Error == nil {
+				e.WillReturnRows(NewRows(s.Columns).AddRows(s.Rows...))
+			}
+			s.apply(e)
+		case "Exec":
+			e := c.ExpectExec(s.SQL)
+			if s.Args != nil {
+				e.WithArgs(s.Args...)
+			}
+			if s.Error == nil {
+				e.WillReturnResult(pgconn.NewCommandTag(s.Result))
+			}
+			s.apply(e)
+		case "Begin":
+			s.apply(c.ExpectBegin())
+		case "Commit":
+			s.apply(c.ExpectCommit())
+		case "Rollback":
+			s.apply(c.ExpectRollback())
+		case "Ping":
+			s.apply(c.ExpectPing())
+		default:
+			return fmt.Errorf("unsupported method '%s' of expectation spec #%d", s.Method, i)
+		}
+	}
+	return nil
+}
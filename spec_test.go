@@ -0,0 +1,54 @@
+package pgxmock
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadExpectations(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	mock, err := NewConn()
+	a.NoError(err)
+	errFail := errors.New("fail")
+	a.NoError(mock.LoadExpectations([]ExpectationSpec{
+		{Method: "Begin"},
+		{Method: "Query", SQL: "SELECT name", Args: []any{1}, Columns: []string{"name"}, Rows: [][]any{{"John"}}},
+		{Method: "Exec", SQL: "UPDATE users", Result: "UPDATE 1", Times: 2},
+		{Method: "Exec", SQL: "DELETE", Error: errFail},
+		{Method: "Ping", Optional: true},
+		{Method: "Commit"},
+	}))
+
+	tx, err := mock.Begin(ctx)
+	a.NoError(err)
+	var name string
+	a.NoError(tx.QueryRow(ctx, "SELECT name FROM users WHERE id = $1", 1).Scan(&name))
+	a.Equal("John", name)
+	for i := 0; i < 2; i++ {
+		tag, err := tx.Exec(ctx, "UPDATE users SET name = 'Jane'")
+		a.NoError(err)
+		a.EqualValues(1, tag.RowsAffected())
+	}
+	_, err = tx.Exec(ctx, "DELETE FROM users")
+	a.ErrorIs(err, errFail)
+	a.NoError(tx.Commit(ctx))
+	a.NoError(mock.ExpectationsWereMet())
+
+	err = mock.LoadExpectations([]ExpectationSpec{{Method: "Ping"}, {Method: "Prepare"}})
+	a.EqualError(err, "unsupported method 'Prepare' of expectation spec #1")
+	err = mock.LoadExpectations([]ExpectationSpec{{Method: "Query", SQL: "SELECT", Columns: []string{"id", "name"}, Rows: [][]any{{1, "John"}, {2}}}})
+	a.EqualError(err, "row #1 of expectation spec #0 has 1 values for 2 columns")
+	a.NoError(mock.Ping(ctx), "optional Ping of the first specs is still expected")
+	a.NoError(mock.Ping(ctx))
+	a.NoError(mock.ExpectationsWereMet())
+
+	a.NoError(mock.LoadExpectations([]ExpectationSpec{{Method: "Exec", SQL: "DELETE", Result: "DELETE 1"}}))
+	_, err = mock.Exec(ctx, "DELETE FROM users WHERE id = $1", 1)
+	a.ErrorContains(err, "expected 0, but got 1 arguments", "spec without args expects no arguments")
+	_, err = mock.Exec(ctx, "DELETE FROM users")
+	a.NoError(err)
+	a.NoError(mock.ExpectationsWereMet())
+}
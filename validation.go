@@ -0,0 +1,227 @@
+package pgxmock
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	pgx "github.com/jackc/pgx/v5"
+)
+
+// validateCall checks the actual SQL and arguments against the enabled
+// validation options before any expectation is matched
+func (c *pgxmock) validateCall(sql string, args []interface{}) error {
+	if c.requireTx && c.openTx.Load() == 0 {
+		return fmt.Errorf("no transaction is open, but all queries are required to run in a transaction: '%s'", sql)
+	}
+	if c.disallowSimpleProtocol && c.execMode(args) == pgx.QueryExecModeSimpleProtocol {
+		return fmt.Errorf("simple protocol is not allowed for sql: '%s'", sql)
+	}
+	if !c.validateArgTypes && !c.validatePlaceholders && c.maxQueryLength <= 0 && c.maxArgCount <= 0 {
+		return nil
+	}
+	if preparedSQL, ok := c.preparedSQL(sql); ok {
+		sql = preparedSQL
+	}
+	sql, args = rewriteArgs(sql, stripQueryOptions(args))
+	if c.maxQueryLength > 0 && len(sql) > c.maxQueryLength {
+		return fmt.Errorf("sql of %d bytes exceeds the limit of %d bytes: '%s'", len(sql), c.maxQueryLength, abbreviate(sql))
+	}
+	if c.maxArgCount > 0 && len(args) > c.maxArgCount {
+		return fmt.Errorf("%d arguments exceed the limit of %d arguments for sql: '%s'", len(args), c.maxArgCount, abbreviate(sql))
+	}
+	if c.validateArgTypes {
+		if err := validateArgTypesFromCasts(sql, args); err != nil {
+			return err
+		}
+	}
+	if c.validatePlaceholders {
+		return placeholdersMatch(sql, args)
+	}
+	return nil
+}
+
+// stripQueryOptions removes leading pgx query options, e.g. pgx.QueryExecMode,
+// which are passed as arguments but are not bound to placeholders
+func stripQueryOptions(args []interface{}) []interface{} {
+	for len(args) > 0 {
+		switch args[0].(type) {
+		case pgx.QueryExecMode, pgx.QueryResultFormats, pgx.QueryResultFormatsByOID:
+			args = args[1:]
+		default:
+			return args
+		}
+	}
+	return args
+}
+
+// execMode returns the query exec mode passed as an argument of the call,
+// otherwise the default one of the configuration set by ConnConfigOption
+func (c *pgxmock) execMode(args []interface{}) pgx.QueryExecMode {
+	mode := pgx.QueryExecModeCacheStatement
+	if c.connConfig != nil && c.connConfig.DefaultQueryExecMode != 0 {
+		mode = c.connConfig.DefaultQueryExecMode
+	}
+	for _, arg := range args {
+		switch arg := arg.(type) {
+		case pgx.QueryExecMode:
+			mode = arg
+		case pgx.QueryResultFormats, pgx.QueryResultFormatsByOID:
+		default:
+			return mode
+		}
+	}
+	return mode
+}
+
+// abbreviate shortens the SQL to be shown in error messages
+func abbreviate(sql string) string {
+	const maxLen = 100
+	if len(sql) <= maxLen {
+		return sql
+	}
+	return sql[:maxLen] + "..."
+}
+
+// rewriteArgs applies the pgx.QueryRewriter argument if present,
+// e.g. pgx.NamedArgs, to get the positional arguments
+func rewriteArgs(sql string, args []interface{}) (string, []interface{}) {
+	if len(args) != 1 {
+		return sql, args
+	}
+	qrw, ok := args[0].(pgx.QueryRewriter)
+	if !ok {
+		return sql, args
+	}
+	rewrittenSQL, rewrittenArgs, err := qrw.RewriteQuery(context.Background(), nil, sql, args)
+	if err != nil {
+		// error will be reported during arguments matching
+		return sql, args
+	}
+	return rewrittenSQL, rewrittenArgs
+}
+
+// argKind is a category of a Go value used to check compatibility with a SQL type
+type argKind int
+
+const (
+	unknownArg argKind = iota
+	integerArg
+	floatArg
+	textArg
+	boolArg
+	bytesArg
+	timeArg
+)
+
+var castRe = regexp.MustCompile(`\$(\d+)::([a-zA-Z_][a-zA-Z0-9_]*)(\[\])?`)
+
+// castKinds lists Go value categories compatible with a SQL type
+var castKinds = map[string][]argKind{
+	"int":         {integerArg},
+	"int2":        {integerArg},
+	"int4":        {integerArg},
+	"int8":        {integerArg},
+	"integer":     {integerArg},
+	"smallint":    {integerArg},
+	"bigint":      {integerArg},
+	"float4":      {integerArg, floatArg},
+	"float8":      {integerArg, floatArg},
+	"real":        {integerArg, floatArg},
+	"numeric":     {integerArg, floatArg, textArg},
+	"decimal":     {integerArg, floatArg, textArg},
+	"text":        {textArg, bytesArg},
+	"varchar":     {textArg, bytesArg},
+	"char":        {textArg, bytesArg},
+	"bpchar":      {textArg, bytesArg},
+	"name":        {textArg, bytesArg},
+	"citext":      {textArg, bytesArg},
+	"uuid":        {textArg, bytesArg},
+	"bool":        {boolArg},
+	"boolean":     {boolArg},
+	"bytea":       {bytesArg},
+	"date":        {timeArg},
+	"timestamp":   {timeArg},
+	"timestamptz": {timeArg},
+}
+
+func kindOf(v interface{}) argKind {
+	if _, ok := v.(time.Time); ok {
+		return timeArg
+	}
+	if _, ok := v.([]byte); ok {
+		return bytesArg
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return integerArg
+	case reflect.Float32, reflect.Float64:
+		return floatArg
+	case reflect.String:
+		return textArg
+	case reflect.Bool:
+		return boolArg
+	}
+	return unknownArg
+}
+
+// validateArgTypesFromCasts checks that arguments having explicit
+// type casts in SQL, e.g. $1::int, are of compatible Go types.
+// Arguments of custom types, e.g. pgtype.* or sql.Valuer, as well
+// as NULL values and array casts are not checked
+func validateArgTypesFromCasts(sql string, args []interface{}) error {
+	for _, m := range castRe.FindAllStringSubmatch(sqlCode(sql), -1) {
+		if m[3] != "" {
+			continue // arrays are not checked
+		}
+		n, _ := strconv.Atoi(m[1])
+		if n < 1 || n > len(args) || args[n-1] == nil {
+			continue
+		}
+		kinds, ok := castKinds[strings.ToLower(m[2])]
+		if !ok {
+			continue
+		}
+		kind := kindOf(args[n-1])
+		if kind == unknownArg {
+			continue
+		}
+		compatible := false
+		for _, k := range kinds {
+			compatible = compatible || k == kind
+		}
+		if !compatible {
+			return fmt.Errorf("argument $%d of type %T is not compatible with the type cast '%s'", n, args[n-1], m[0])
+		}
+	}
+	return nil
+}
+
+// placeholders returns sorted distinct indexes of positional placeholders,
+// e.g. $1, used in SQL outside of literals, quoted identifiers and comments
+func placeholders(sql string) []int {
+	idx := placeholderOrder(sql)
+	slices.Sort(idx)
+	return idx
+}
+
+// placeholdersMatch checks that placeholders of SQL are numbered from $1
+// without gaps and every argument is bound to a placeholder
+func placeholdersMatch(sql string, args []interface{}) error {
+	idx := placeholders(sql)
+	for i, n := range idx {
+		if n != i+1 {
+			return fmt.Errorf("placeholder $%d is missing in sql: '%s'", i+1, sql)
+		}
+	}
+	if len(idx) != len(args) {
+		return fmt.Errorf("sql has %d placeholders, but %d arguments are passed", len(idx), len(args))
+	}
+	return nil
+}
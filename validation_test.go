@@ -0,0 +1,210 @@
+package pgxmock
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	pgx "github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateArgTypesFromCasts(t *testing.T) {
+	t.Parallel()
+	mock, err := NewConn(ValidateArgTypesFromCasts(true))
+	a := assert.New(t)
+	a.NoError(err)
+
+	sql := "INSERT INTO users(id, name) VALUES ($1::int, $2::text)"
+	namedSQL := "INSERT INTO users(id, name) VALUES (@id::int, @name::text)"
+	mock.ExpectExec("INSERT INTO users").
+		WithArgs(AnyArg(), AnyArg()).
+		WillReturnResult(NewResult("INSERT", 1)).
+		Times(3)
+
+	_, err = mock.Exec(ctx, sql, 42, "John")
+	a.NoError(err)
+	_, err = mock.Exec(ctx, sql, nil, pgtype.Text{String: "John", Valid: true})
+	a.NoError(err, "NULL and custom types should not be checked")
+	_, err = mock.Exec(ctx, namedSQL, pgx.NamedArgs{"id": 42, "name": "John"})
+	a.NoError(err)
+
+	_, err = mock.Exec(ctx, sql, "42", "John")
+	a.ErrorContains(err, "argument $1 of type string is not compatible")
+	_, err = mock.Exec(ctx, namedSQL, pgx.NamedArgs{"id": "42", "name": "John"})
+	a.Error(err, "named arguments should be checked after rewriting")
+
+	mock.ExpectQuery("SELECT").
+		WithArgs(AnyArg()).
+		WillReturnRows(NewRows([]string{"id"}))
+	_, err = mock.Query(ctx, "SELECT id FROM users WHERE created < $1::timestamptz", 42)
+	a.Error(err)
+	_, err = mock.Query(ctx, "SELECT id FROM users WHERE created < $1::timestamptz", time.Now())
+	a.NoError(err)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestValidateArgTypesFromCastsDisabled(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	mock.ExpectExec("INSERT INTO users").
+		WithArgs("42").
+		WillReturnResult(NewResult("INSERT", 1))
+	_, err := mock.Exec(ctx, "INSERT INTO users(id) VALUES ($1::int)", "42")
+	assert.NoError(t, err)
+}
+
+func TestPlaceholders(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	a.Equal([]int{1, 2}, placeholders("SELECT * FROM t WHERE id = $2 AND status = $1 OR parent = $2"))
+	a.Empty(placeholders("SELECT '$1', \"$2\" FROM t WHERE price = $$"))
+	a.Equal([]int{1, 10}, placeholders("SELECT $1::int, $10"))
+	a.Equal([]int{1}, placeholders("CREATE FUNCTION f() RETURNS int AS $$ SELECT $2 $$ LANGUAGE sql; SELECT f($1)"))
+	a.Equal([]int{1}, placeholders("SELECT $body$ $3 $body$, $1 -- filter by $2\n/* outer /* nested $4 */ $5 */"))
+	a.Equal([]int{1}, placeholders(`SELECT E'it\'s $2', 'it''s $3', "col$4" FROM t WHERE id = $1`))
+	a.Equal([]int{2}, placeholders("SELECT tbl$1$ FROM t WHERE id = $2"), "$ inside identifier does not start dollar-quoting")
+	a.NoError(validateArgTypesFromCasts("SELECT $1::int -- $2::int", []any{1, "text"}))
+	a.Equal("SELECT $2 FROM t /* $1 */", renumberPlaceholders("SELECT $1 FROM t /* $1 */", func(int) int { return 2 }))
+
+	a.NoError(placeholdersMatch("WHERE id = $1 AND status = $2", []any{1, "new"}))
+	a.ErrorContains(placeholdersMatch("WHERE id = $1 AND status = $3", []any{1, "new"}), "placeholder $2 is missing")
+	a.ErrorContains(placeholdersMatch("WHERE id = $1", []any{1, "new"}), "sql has 1 placeholders, but 2 arguments are passed")
+}
+
+func TestWithPlaceholderCount(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn()
+	a := assert.New(t)
+
+	e := mock.ExpectQuery("SELECT (.+) FROM orders").WithPlaceholderCount(2).
+		WillReturnRows(NewRows([]string{"id"}))
+	a.Contains(e.String(), "is with 2 placeholders bound to arguments")
+	_, err := mock.Query(ctx, "SELECT id FROM orders WHERE id = $1", 1)
+	a.ErrorContains(err, "expected 2 placeholders, but sql has 1")
+	_, err = mock.Query(ctx, "SELECT id FROM orders WHERE id = $1 AND status = $2", 1)
+	a.ErrorContains(err, "sql has 2 placeholders, but 1 arguments are passed")
+	_, err = mock.Query(ctx, "SELECT id FROM orders WHERE id = $1 AND status = $2", 1, "new")
+	a.NoError(err)
+
+	mock.ExpectExec("UPDATE orders").WithPlaceholderCount(2).WillReturnResult(NewResult("UPDATE", 1))
+	_, err = mock.Exec(ctx, "UPDATE orders SET status = @status WHERE id = @id", pgx.NamedArgs{"id": 1, "status": "new"})
+	a.NoError(err, "placeholders must be checked after rewriting")
+
+	mock.ExpectExec("DELETE FROM orders").WithPlaceholderCount(1).WillReturnResult(NewResult("DELETE", 1))
+	_, err = mock.Exec(ctx, "DELETE FROM orders WHERE id = $1", pgx.QueryExecModeSimpleProtocol, 1)
+	a.NoError(err, "query options must not be counted as arguments")
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestValidatePlaceholderCount(t *testing.T) {
+	t.Parallel()
+	mock, _ := NewConn(ValidatePlaceholderCount(true))
+	a := assert.New(t)
+	mock.MatchExpectationsInOrder(false)
+
+	mock.ExpectExec("UPDATE orders").WithArgs(AnyArg(), AnyArg()).
+		WillReturnResult(NewResult("UPDATE", 1)).Times(3)
+	_, err := mock.Exec(ctx, "UPDATE orders SET status = $1 WHERE id = $2 AND region = $3", "paid", 42)
+	a.ErrorContains(err, "sql has 3 placeholders, but 2 arguments are passed")
+	_, err = mock.Exec(ctx, "UPDATE orders SET status = $1 WHERE id = $2", "paid", 42)
+	a.NoError(err)
+	a.NoError(placeholdersMatch("UPDATE orders SET status = $1 WHERE id = $2",
+		stripQueryOptions([]any{pgx.QueryExecModeSimpleProtocol, "paid", 42})), "query options must not be counted")
+	_, err = mock.Exec(ctx, "UPDATE orders SET status = @status WHERE id = @id", pgx.NamedArgs{"status": "paid", "id": 42})
+	a.NoError(err, "named arguments must be rewritten")
+
+	mock.ExpectPrepare("upd", "UPDATE orders")
+	_, err = mock.Prepare(ctx, "upd", "UPDATE orders SET status = $1 WHERE id = $2")
+	a.NoError(err)
+	_, err = mock.Exec(ctx, "upd", "paid", 42)
+	a.NoError(err, "prepared statement SQL must be checked")
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestDisallowSimpleProtocol(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	mock, _ := NewConn(DisallowSimpleProtocol(true))
+	mock.ExpectExec("UPDATE").WithArgs(pgx.QueryExecModeExec, "john").WillReturnResult(NewResult("UPDATE", 1))
+	mock.ExpectExec("UPDATE").WithArgs("john").WillReturnResult(NewResult("UPDATE", 1))
+	mock.ExpectQuery("SELECT").WillReturnRows(NewRows([]string{"id"}))
+
+	_, err := mock.Exec(ctx, "UPDATE users SET name = $1", pgx.QueryExecModeSimpleProtocol, "john")
+	a.ErrorContains(err, "simple protocol is not allowed for sql: 'UPDATE users SET name = $1'")
+	_, err = mock.Query(ctx, "SELECT id FROM users", pgx.QueryResultFormats{}, pgx.QueryExecModeSimpleProtocol)
+	a.ErrorContains(err, "simple protocol is not allowed")
+	_, err = mock.Exec(ctx, "UPDATE users SET name = $1", pgx.QueryExecModeExec, "john")
+	a.NoError(err)
+	_, err = mock.Exec(ctx, "UPDATE users SET name = $1", "john")
+	a.NoError(err)
+	rows, err := mock.Query(ctx, "SELECT id FROM users")
+	a.NoError(err)
+	rows.Close()
+	a.NoError(mock.ExpectationsWereMet())
+
+	config := &pgx.ConnConfig{DefaultQueryExecMode: pgx.QueryExecModeSimpleProtocol}
+	mock, _ = NewConn(DisallowSimpleProtocol(true), ConnConfigOption(config))
+	mock.ExpectPing()
+	_, err = mock.Exec(ctx, "UPDATE users SET name = 'john'")
+	a.ErrorContains(err, "simple protocol is not allowed", "default exec mode of the config must be respected")
+}
+
+func TestRequireAllQueriesInTx(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	mock, _ := NewPool()
+	mock.RequireAllQueriesInTx(true)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE users").WillReturnResult(NewResult("UPDATE", 1))
+	mock.ExpectQuery("SELECT name").WillReturnRows(NewRows([]string{"name"}).AddRow("john"))
+	mock.ExpectCommit()
+
+	_, err := mock.Exec(ctx, "UPDATE users SET name = 'john'")
+	a.ErrorContains(err, "no transaction is open, but all queries are required to run in a transaction")
+	tx, err := mock.Begin(ctx)
+	a.NoError(err)
+	_, err = tx.Exec(ctx, "UPDATE users SET name = 'john'")
+	a.NoError(err)
+	var name string
+	a.NoError(tx.QueryRow(ctx, "SELECT name FROM users").Scan(&name))
+	a.NoError(tx.Commit(ctx))
+	a.Error(mock.QueryRow(ctx, "SELECT name FROM users").Scan(&name), "transaction is finished")
+	a.NoError(mock.ExpectationsWereMet())
+
+	mock.RequireAllQueriesInTx(false)
+	mock.ExpectExec("UPDATE users").WillReturnResult(NewResult("UPDATE", 1))
+	_, err = mock.Exec(ctx, "UPDATE users SET name = 'john'")
+	a.NoError(err)
+}
+
+func TestMaxQueryLengthAndArgCount(t *testing.T) {
+	t.Parallel()
+	a := assert.New(t)
+	mock, _ := NewConn(MaxQueryLength(200), MaxArgCount(3))
+	mock.ExpectQuery("SELECT id FROM users").WithArgs(1, 2, 3).WillReturnRows(NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT id FROM users").WithArgs(pgx.QueryExecModeExec, 1).WillReturnRows(NewRows([]string{"id"}))
+
+	ids := make([]string, 100)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("$%d", i+1)
+	}
+	huge := "SELECT id FROM users WHERE id IN (" + strings.Join(ids, ", ") + ")"
+	_, err := mock.Query(ctx, huge)
+	a.ErrorContains(err, fmt.Sprintf("sql of %d bytes exceeds the limit of 200 bytes: 'SELECT id FROM users WHERE id IN ($1, $2,", len(huge)))
+	a.ErrorContains(err, "...'", "sql must be abbreviated")
+	_, err = mock.Query(ctx, "SELECT id FROM users WHERE id IN ($1, $2, $3, $4)", 1, 2, 3, 4)
+	a.EqualError(err, "4 arguments exceed the limit of 3 arguments for sql: 'SELECT id FROM users WHERE id IN ($1, $2, $3, $4)'")
+	_, err = mock.Query(ctx, "SELECT id FROM users WHERE id IN (@a, @b, @c, @d)", pgx.NamedArgs{"a": 1, "b": 2, "c": 3, "d": 4})
+	a.ErrorContains(err, "4 arguments exceed the limit", "rewritten arguments must be counted")
+
+	rows, err := mock.Query(ctx, "SELECT id FROM users WHERE id IN ($1, $2, $3)", 1, 2, 3)
+	a.NoError(err)
+	rows.Close()
+	rows, err = mock.Query(ctx, "SELECT id FROM users WHERE id = $1", pgx.QueryExecModeExec, 1)
+	a.NoError(err, "query options must not be counted")
+	rows.Close()
+	a.NoError(mock.ExpectationsWereMet())
+}